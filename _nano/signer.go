@@ -0,0 +1,52 @@
+package nano
+
+import (
+	"github.com/pkg/errors"
+
+	crypto "github.com/tendermint/go-crypto"
+	"github.com/tendermint/go-crypto/keys"
+)
+
+// Signer is a keys.SignerBackend for the cosmos app on a Ledger Nano,
+// to sign with ledger keys through dbKeybase.WithSigner(keys.KeyLedger,
+// Signer{}).
+//
+// The app has a single key and no derivation paths, so only ledger keys
+// with an empty path are supported.
+type Signer struct{}
+
+var _ keys.SignerBackend = Signer{}
+
+// PubKey reads the public key from the device.
+func (Signer) PubKey(info keys.Info) (crypto.PubKey, error) {
+	if err := checkLedgerKey(info); err != nil {
+		return nil, err
+	}
+	var pk PrivKeyLedgerEd25519
+	return pk.forceGetPubKey()
+}
+
+// Sign signs msg on the device. The keybase checks the signature
+// against info.PubKey, so signing fails if another device is plugged
+// in.
+func (Signer) Sign(info keys.Info, msg []byte) (crypto.Signature, error) {
+	if err := checkLedgerKey(info); err != nil {
+		return nil, err
+	}
+	dev, err := getLedger()
+	if err != nil {
+		return nil, errors.New("Can't connect to ledger device")
+	}
+	_, sig, err := signLedger(dev, msg)
+	return sig, err
+}
+
+func checkLedgerKey(info keys.Info) error {
+	if info.Kind != keys.KeyLedger || info.Ledger == nil {
+		return errors.Errorf("Key %s is not a ledger key", info.Name)
+	}
+	if info.Ledger.Path != "" {
+		return errors.Errorf("The cosmos app has no derivation paths, got %q", info.Ledger.Path)
+	}
+	return nil
+}
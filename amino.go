@@ -10,27 +10,59 @@ func init() {
 	// NOTE: It's important that there be no conflicts here,
 	// as that would change the canonical representations,
 	// and therefore change the address.
-	// TODO: Add feature to go-amino to ensure that there
-	// are no conflicts.
+	// RegisterKeyType panics on any conflict.
+	RegisterKeyType(KeyType{
+		Name:     "ed25519",
+		SeedByte: 0x01,
+		PubKey:   AminoType{"tendermint/PubKeyEd25519", PubKeyEd25519{}},
+		PrivKey:  AminoType{"tendermint/PrivKeyEd25519", PrivKeyEd25519{}},
+		Signatures: []AminoType{
+			{"tendermint/SignatureKeyEd25519", SignatureEd25519{}},
+		},
+		GenPrivKey:           func() PrivKey { return GenPrivKeyEd25519() },
+		GenPrivKeyFromSecret: func(secret []byte) PrivKey { return GenPrivKeyEd25519FromSecret(secret) },
+	})
+	RegisterKeyType(KeyType{
+		Name:     "secp256k1",
+		SeedByte: 0x02,
+		PubKey:   AminoType{"tendermint/PubKeySecp256k1", PubKeySecp256k1{}},
+		PrivKey:  AminoType{"tendermint/PrivKeySecp256k1", PrivKeySecp256k1{}},
+		Signatures: []AminoType{
+			{"tendermint/SignatureKeySecp256k1", SignatureSecp256k1{}},
+			{"tendermint/SignatureSecp256k1Fixed", SignatureSecp256k1Fixed{}},
+			{"tendermint/SignatureSecp256k1Recoverable", SignatureSecp256k1Recoverable{}},
+		},
+		GenPrivKey:           func() PrivKey { return GenPrivKeySecp256k1() },
+		GenPrivKeyFromSecret: func(secret []byte) PrivKey { return GenPrivKeySecp256k1FromSecret(secret) },
+	})
+	RegisterKeyType(KeyType{
+		Name:     "schnorr",
+		SeedByte: 0x03,
+		PubKey:   AminoType{"tendermint/PubKeySchnorr", PubKeySchnorr{}},
+		PrivKey:  AminoType{"tendermint/PrivKeySchnorr", PrivKeySchnorr{}},
+		Signatures: []AminoType{
+			{"tendermint/SignatureSchnorr", SignatureSchnorr{}},
+		},
+		GenPrivKey:           func() PrivKey { return GenPrivKeySchnorr() },
+		GenPrivKeyFromSecret: func(secret []byte) PrivKey { return GenPrivKeySchnorrFromSecret(secret) },
+	})
+	RegisterKeyType(KeyType{
+		Name:     "bls12381",
+		SeedByte: 0x04,
+		PubKey:   AminoType{"tendermint/PubKeyBLS12381", PubKeyBLS12381{}},
+		PrivKey:  AminoType{"tendermint/PrivKeyBLS12381", PrivKeyBLS12381{}},
+		Signatures: []AminoType{
+			{"tendermint/SignatureBLS12381", SignatureBLS12381{}},
+		},
+		GenPrivKey:           func() PrivKey { return GenPrivKeyBLS12381() },
+		GenPrivKeyFromSecret: func(secret []byte) PrivKey { return GenPrivKeyBLS12381FromSecret(secret) },
+	})
+	RegisterKeyType(KeyType{
+		Name:   "multisig-threshold",
+		PubKey: AminoType{"tendermint/PubKeyMultisigThreshold", PubKeyMultisigThreshold{}},
+		Signatures: []AminoType{
+			{"tendermint/SignatureMultisig", SignatureMultisig{}},
+		},
+	})
 	RegisterAmino(cdc)
 }
-
-func RegisterAmino(cdc *amino.Codec) {
-	cdc.RegisterInterface((*PubKey)(nil), nil)
-	cdc.RegisterConcrete(PubKeyEd25519{},
-		"tendermint/PubKeyEd25519", nil)
-	cdc.RegisterConcrete(PubKeySecp256k1{},
-		"tendermint/PubKeySecp256k1", nil)
-
-	cdc.RegisterInterface((*PrivKey)(nil), nil)
-	cdc.RegisterConcrete(PrivKeyEd25519{},
-		"tendermint/PrivKeyEd25519", nil)
-	cdc.RegisterConcrete(PrivKeySecp256k1{},
-		"tendermint/PrivKeySecp256k1", nil)
-
-	cdc.RegisterInterface((*Signature)(nil), nil)
-	cdc.RegisterConcrete(SignatureEd25519{},
-		"tendermint/SignatureKeyEd25519", nil)
-	cdc.RegisterConcrete(SignatureSecp256k1{},
-		"tendermint/SignatureKeySecp256k1", nil)
-}
@@ -0,0 +1,180 @@
+package crypto
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// Ciphertexts produced by EncryptTo start with one of these bytes,
+// which identifies the scheme used to create them.
+const (
+	// 0x01 || ephemeral Curve25519 key (32 bytes) || box
+	// The box is compatible with libsodium's crypto_box_seal.
+	asymSealedBox byte = 0x01
+	// 0x02 || compressed ephemeral secp256k1 key (33 bytes) || secretbox
+	// with a zero nonce, see eciesKey.
+	asymECIESSecp256k1 byte = 0x02
+)
+
+var eciesInfo = []byte("tendermint/ECIES-secp256k1")
+
+// EncryptTo encrypts plaintext so that only the holder of the private
+// key of pub can decrypt it, see DecryptWith.
+// Ed25519 keys use an anonymous NaCl sealed box over the keys'
+// Curve25519 form; secp256k1 keys use ECIES with HKDF-SHA256 and
+// NaCl secretbox.
+// NOTE: the sender is not authenticated. Sign the plaintext if the
+// recipient needs to know who sent it.
+func EncryptTo(pub PubKey, plaintext []byte) ([]byte, error) {
+	switch pub := pub.(type) {
+	case PubKeyEd25519:
+		return sealBox(pub, plaintext)
+	case PubKeySecp256k1:
+		return encryptECIES(pub, plaintext)
+	default:
+		return nil, fmt.Errorf("Encryption is not supported for %T", pub)
+	}
+}
+
+// DecryptWith decrypts a ciphertext created by EncryptTo for the public
+// key of priv.
+func DecryptWith(priv PrivKey, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) == 0 {
+		return nil, errors.New("Ciphertext is too short")
+	}
+	switch priv := priv.(type) {
+	case PrivKeyEd25519:
+		if ciphertext[0] != asymSealedBox {
+			return nil, fmt.Errorf("Unexpected ciphertext type %#x for %T", ciphertext[0], priv)
+		}
+		return openBox(priv, ciphertext[1:])
+	case PrivKeySecp256k1:
+		if ciphertext[0] != asymECIESSecp256k1 {
+			return nil, fmt.Errorf("Unexpected ciphertext type %#x for %T", ciphertext[0], priv)
+		}
+		return decryptECIES(priv, ciphertext[1:])
+	default:
+		return nil, fmt.Errorf("Decryption is not supported for %T", priv)
+	}
+}
+
+//-------------------------------------
+// Sealed box
+
+// sealBoxNonce returns blake2b-192(epk || pk), as libsodium does.
+func sealBoxNonce(ephemeralPub, pub *[32]byte) *[24]byte {
+	hasher, err := blake2b.New(24, nil)
+	if err != nil {
+		panic(err)
+	}
+	hasher.Write(ephemeralPub[:])
+	hasher.Write(pub[:])
+	nonce := new([24]byte)
+	copy(nonce[:], hasher.Sum(nil))
+	return nonce
+}
+
+func sealBox(pub PubKeyEd25519, plaintext []byte) ([]byte, error) {
+	pubCurve := pub.ToCurve25519()
+	if pubCurve == nil {
+		return nil, errors.New("Invalid Ed25519 public key")
+	}
+	ephemeralPub, ephemeralPriv, err := box.GenerateKey(CReader())
+	if err != nil {
+		return nil, err
+	}
+	var dh [32]byte
+	curve25519.ScalarMult(&dh, ephemeralPriv, pubCurve)
+	if subtle.ConstantTimeCompare(dh[:], make([]byte, 32)) == 1 {
+		return nil, errors.New("Public key is a low order point")
+	}
+
+	out := make([]byte, 1+32, 1+32+box.Overhead+len(plaintext))
+	out[0] = asymSealedBox
+	copy(out[1:], ephemeralPub[:])
+	nonce := sealBoxNonce(ephemeralPub, pubCurve)
+	return box.Seal(out, plaintext, nonce, pubCurve, ephemeralPriv), nil
+}
+
+func openBox(priv PrivKeyEd25519, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 32+box.Overhead {
+		return nil, errors.New("Ciphertext is too short")
+	}
+	pubCurve := priv.PubKey().(PubKeyEd25519).ToCurve25519()
+	if pubCurve == nil {
+		return nil, errors.New("Invalid Ed25519 private key")
+	}
+	ephemeralPub := new([32]byte)
+	copy(ephemeralPub[:], ciphertext[:32])
+	nonce := sealBoxNonce(ephemeralPub, pubCurve)
+	plaintext, ok := box.Open(nil, ciphertext[32:], nonce, ephemeralPub, priv.ToCurve25519())
+	if !ok {
+		return nil, errors.New("Ciphertext decryption failed")
+	}
+	return plaintext, nil
+}
+
+//-------------------------------------
+// ECIES
+
+// eciesKey derives the secretbox key from the shared secret, binding
+// it to both the ephemeral and the recipient's public key.
+// Every message has its own ephemeral key, hence its own secretbox key,
+// so the secretbox nonce can be fixed to zero.
+func eciesKey(secret []byte, ephemeralPub, pub PubKeySecp256k1) (*[secretLen]byte, error) {
+	salt := make([]byte, 0, 2*len(pub))
+	salt = append(salt, ephemeralPub[:]...)
+	salt = append(salt, pub[:]...)
+	bz, err := DeriveKey(secret, salt, eciesInfo, secretLen)
+	if err != nil {
+		return nil, err
+	}
+	key := new([secretLen]byte)
+	copy(key[:], bz)
+	return key, nil
+}
+
+func encryptECIES(pub PubKeySecp256k1, plaintext []byte) ([]byte, error) {
+	ephemeral := GenPrivKeySecp256k1()
+	secret, err := SharedSecret(ephemeral, pub)
+	if err != nil {
+		return nil, err
+	}
+	ephemeralPub := ephemeral.PubKey().(PubKeySecp256k1)
+	key, err := eciesKey(secret, ephemeralPub, pub)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, 1+len(ephemeralPub)+secretbox.Overhead+len(plaintext))
+	out = append(out, asymECIESSecp256k1)
+	out = append(out, ephemeralPub[:]...)
+	return secretbox.Seal(out, plaintext, new([nonceLen]byte), key), nil
+}
+
+func decryptECIES(priv PrivKeySecp256k1, ciphertext []byte) ([]byte, error) {
+	var ephemeralPub PubKeySecp256k1
+	if len(ciphertext) < len(ephemeralPub)+secretbox.Overhead {
+		return nil, errors.New("Ciphertext is too short")
+	}
+	copy(ephemeralPub[:], ciphertext)
+	secret, err := SharedSecret(priv, ephemeralPub)
+	if err != nil {
+		return nil, err
+	}
+	key, err := eciesKey(secret, ephemeralPub, priv.PubKey().(PubKeySecp256k1))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, ok := secretbox.Open(nil, ciphertext[len(ephemeralPub):], new([nonceLen]byte), key)
+	if !ok {
+		return nil, errors.New("Ciphertext decryption failed")
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/nacl/box"
+)
+
+func TestEncryptToDecryptWith(t *testing.T) {
+	privKeys := []PrivKey{GenPrivKeyEd25519(), GenPrivKeySecp256k1()}
+	plaintext := []byte("sweet chili")
+
+	for _, priv := range privKeys {
+		ciphertext, err := EncryptTo(priv.PubKey(), plaintext)
+		require.Nil(t, err, "%+v", err)
+
+		decrypted, err := DecryptWith(priv, ciphertext)
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, plaintext, decrypted)
+
+		// a fresh ephemeral key is used every time
+		again, err := EncryptTo(priv.PubKey(), plaintext)
+		require.Nil(t, err, "%+v", err)
+		assert.NotEqual(t, ciphertext, again)
+
+		// empty plaintexts round trip as well
+		ciphertext, err = EncryptTo(priv.PubKey(), nil)
+		require.Nil(t, err, "%+v", err)
+		decrypted, err = DecryptWith(priv, ciphertext)
+		require.Nil(t, err, "%+v", err)
+		assert.Empty(t, decrypted)
+	}
+}
+
+func TestDecryptWithFailures(t *testing.T) {
+	privKeys := []PrivKey{GenPrivKeyEd25519(), GenPrivKeySecp256k1()}
+	plaintext := []byte("sweet chili")
+
+	for _, priv := range privKeys {
+		ciphertext, err := EncryptTo(priv.PubKey(), plaintext)
+		require.Nil(t, err, "%+v", err)
+
+		// wrong recipient
+		var other PrivKey
+		switch priv.(type) {
+		case PrivKeyEd25519:
+			other = GenPrivKeyEd25519()
+		default:
+			other = GenPrivKeySecp256k1()
+		}
+		_, err = DecryptWith(other, ciphertext)
+		assert.NotNil(t, err)
+
+		// tampered ciphertext
+		tampered := append([]byte{}, ciphertext...)
+		tampered[len(tampered)-1] ^= 0x01
+		_, err = DecryptWith(priv, tampered)
+		assert.NotNil(t, err)
+
+		// truncated ciphertext
+		for _, n := range []int{0, 1, 20, len(ciphertext) - 1} {
+			_, err = DecryptWith(priv, ciphertext[:n])
+			assert.NotNil(t, err, "length %d", n)
+		}
+	}
+
+	// the type byte must match the key
+	ciphertext, err := EncryptTo(GenPrivKeyEd25519().PubKey(), plaintext)
+	require.Nil(t, err, "%+v", err)
+	_, err = DecryptWith(GenPrivKeySecp256k1(), ciphertext)
+	assert.NotNil(t, err)
+
+	_, err = EncryptTo(GenPrivKeySchnorr().PubKey(), plaintext)
+	assert.NotNil(t, err)
+}
+
+// Sealed boxes can be opened by any NaCl implementation
+// that knows the recipient's Curve25519 key.
+func TestSealedBoxFormat(t *testing.T) {
+	priv := GenPrivKeyEd25519()
+	pubCurve := priv.PubKey().(PubKeyEd25519).ToCurve25519()
+	plaintext := []byte("sweet chili")
+
+	ciphertext, err := EncryptTo(priv.PubKey(), plaintext)
+	require.Nil(t, err, "%+v", err)
+	require.Equal(t, asymSealedBox, ciphertext[0])
+	assert.Len(t, ciphertext, 1+32+box.Overhead+len(plaintext))
+
+	ephemeralPub := new([32]byte)
+	copy(ephemeralPub[:], ciphertext[1:33])
+	opened, ok := box.Open(nil, ciphertext[33:], sealBoxNonce(ephemeralPub, pubCurve), ephemeralPub, priv.ToCurve25519())
+	require.True(t, ok)
+	assert.Equal(t, plaintext, opened)
+}
@@ -0,0 +1,172 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha512"
+
+	"filippo.io/edwards25519"
+)
+
+// BatchVerifier verifies many (PubKey, msg, Signature) triples at once.
+//
+// Ed25519 entries are checked together with a single randomized
+// multi-scalar multiplication, which is considerably cheaper than
+// verifying them one by one. Entries of any other key type are
+// verified individually with VerifyBytes.
+//
+// NOTE: The batch uses the cofactored equation
+// [8][s]B = [8]R + [8][k]A, while PubKeyEd25519.VerifyBytes checks
+// [s]B = R + [k]A. Entries the batch can't decode exactly like
+// VerifyBytes, e.g. with a non-canonical encoding of R or A, are
+// verified individually, and when the batch fails, every entry is
+// checked again with VerifyBytes. So the results only differ when the
+// batch passes with a signature for which [s]B - R - [k]A is a point of
+// small order other than the identity. Such signatures are crafted by
+// adding a small order point to R or A; no honest signer produces
+// them.
+type BatchVerifier struct {
+	entries []batchEntry
+}
+
+type batchEntry struct {
+	pubKey PubKey
+	msg    []byte
+	sig    Signature
+}
+
+// NewBatchVerifier returns an empty BatchVerifier.
+func NewBatchVerifier() *BatchVerifier {
+	return &BatchVerifier{}
+}
+
+// Add queues a signature for verification.
+// msg is not copied, so it must not be modified until Verify returns.
+func (bv *BatchVerifier) Add(pubKey PubKey, msg []byte, sig Signature) {
+	bv.entries = append(bv.entries, batchEntry{pubKey, msg, sig})
+}
+
+// Len returns the number of queued signatures.
+func (bv *BatchVerifier) Len() int {
+	return len(bv.entries)
+}
+
+// Verify checks all queued signatures. It returns true iff every
+// signature is valid. The returned slice holds the validity of each
+// entry, in the order they were added, so callers can find the
+// offending entries when the batch does not verify.
+func (bv *BatchVerifier) Verify() (bool, []bool) {
+	valid := make([]bool, len(bv.entries))
+	allValid := true
+
+	// Collect the Ed25519 entries that can take part in the batch,
+	// and verify everything else one at a time.
+	var batch []int
+	var parsed []ed25519BatchItem
+	for i, e := range bv.entries {
+		if item, ok := parseEd25519BatchItem(e); ok {
+			batch = append(batch, i)
+			parsed = append(parsed, item)
+			continue
+		}
+		valid[i] = e.pubKey != nil && e.pubKey.VerifyBytes(e.msg, e.sig)
+		allValid = allValid && valid[i]
+	}
+
+	if len(batch) == 0 {
+		return allValid, valid
+	}
+	if verifyEd25519Batch(parsed) {
+		for _, i := range batch {
+			valid[i] = true
+		}
+		return allValid, valid
+	}
+
+	// The batch failed, so find out which entries are to blame.
+	for _, i := range batch {
+		e := bv.entries[i]
+		valid[i] = e.pubKey.VerifyBytes(e.msg, e.sig)
+		allValid = allValid && valid[i]
+	}
+	return allValid, valid
+}
+
+//-------------------------------------
+
+type ed25519BatchItem struct {
+	A, R *edwards25519.Point
+	s, k *edwards25519.Scalar
+}
+
+// parseEd25519BatchItem decodes an Ed25519 entry into curve points and
+// scalars. It returns false for anything that is not a well formed
+// Ed25519 signature with canonical encodings; those entries are left to
+// VerifyBytes.
+func parseEd25519BatchItem(e batchEntry) (item ed25519BatchItem, ok bool) {
+	pubKey, ok := e.pubKey.(PubKeyEd25519)
+	if !ok {
+		return item, false
+	}
+	sig, ok := e.sig.(SignatureEd25519)
+	if !ok {
+		return item, false
+	}
+
+	var err error
+	if item.A, err = new(edwards25519.Point).SetBytes(pubKey[:]); err != nil || !bytes.Equal(item.A.Bytes(), pubKey[:]) {
+		return item, false
+	}
+	if item.R, err = new(edwards25519.Point).SetBytes(sig[:32]); err != nil || !bytes.Equal(item.R.Bytes(), sig[:32]) {
+		return item, false
+	}
+	if item.s, err = edwards25519.NewScalar().SetCanonicalBytes(sig[32:]); err != nil {
+		return item, false
+	}
+
+	// k = SHA512(R || A || msg) mod l
+	h := sha512.New()
+	h.Write(sig[:32])
+	h.Write(pubKey[:])
+	h.Write(e.msg)
+	if item.k, err = edwards25519.NewScalar().SetUniformBytes(h.Sum(nil)); err != nil {
+		return item, false
+	}
+	return item, true
+}
+
+// verifyEd25519Batch checks
+//
+//	[8](-[sum z_i * s_i]B + sum [z_i]R_i + sum [z_i * k_i]A_i) == 0
+//
+// for random 128 bit z_i, which holds for all items iff (with
+// overwhelming probability) [8][s_i]B == [8]R_i + [8][k_i]A_i for each i.
+func verifyEd25519Batch(items []ed25519BatchItem) bool {
+	scalars := make([]*edwards25519.Scalar, 0, 2*len(items)+1)
+	points := make([]*edwards25519.Point, 0, 2*len(items)+1)
+
+	bScalar := edwards25519.NewScalar()
+	for _, item := range items {
+		z := randomBatchScalar()
+		bScalar.MultiplyAdd(z, item.s, bScalar)
+		scalars = append(scalars, z, edwards25519.NewScalar().Multiply(z, item.k))
+		points = append(points, item.R, item.A)
+	}
+	scalars = append(scalars, bScalar.Negate(bScalar))
+	points = append(points, edwards25519.NewGeneratorPoint())
+
+	check := new(edwards25519.Point).VarTimeMultiScalarMult(scalars, points)
+	check.MultByCofactor(check)
+	return check.Equal(edwards25519.NewIdentityPoint()) == 1
+}
+
+// randomBatchScalar returns a random 128 bit scalar.
+func randomBatchScalar() *edwards25519.Scalar {
+	var buf [32]byte
+	copy(buf[:16], CRandBytes(16))
+	z, err := edwards25519.NewScalar().SetCanonicalBytes(buf[:])
+	if err != nil {
+		// 128 bit values are always canonical.
+		panic(err)
+	}
+	return z
+}
@@ -0,0 +1,250 @@
+package crypto
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/ed25519"
+)
+
+func TestBatchVerifierValid(t *testing.T) {
+	for _, n := range []int{1, 2, 16, 64} {
+		bv := NewBatchVerifier()
+		for i := 0; i < n; i++ {
+			var priv PrivKey = GenPrivKeyEd25519()
+			if i%4 == 3 {
+				priv = GenPrivKeySecp256k1()
+			}
+			msg := CRandBytes(32)
+			bv.Add(priv.PubKey(), msg, MustSign(priv, msg))
+		}
+		require.Equal(t, n, bv.Len())
+
+		ok, valid := bv.Verify()
+		assert.True(t, ok, "batch of %d", n)
+		require.Equal(t, n, len(valid))
+		for i, v := range valid {
+			assert.True(t, v, "batch of %d, entry %d", n, i)
+		}
+	}
+}
+
+func TestBatchVerifierReportsFailures(t *testing.T) {
+	bv := NewBatchVerifier()
+	bad := map[int]bool{}
+	for i := 0; i < 20; i++ {
+		var priv PrivKey = GenPrivKeyEd25519()
+		if i%5 == 4 {
+			priv = GenPrivKeySecp256k1()
+		}
+		msg := CRandBytes(32)
+		sig := MustSign(priv, msg)
+		switch i {
+		case 3:
+			// signature over a different message
+			sig = MustSign(priv, CRandBytes(32))
+			bad[i] = true
+		case 7:
+			// mutated Ed25519 signature
+			sigEd := sig.(SignatureEd25519)
+			sigEd[40] ^= 0x01
+			sig = sigEd
+			bad[i] = true
+		case 9:
+			// mutated secp256k1 signature
+			sigSecp := append(SignatureSecp256k1{}, sig.(SignatureSecp256k1)...)
+			sigSecp[10] ^= 0x01
+			sig = sigSecp
+			bad[i] = true
+		case 12:
+			// signature of the wrong type
+			sig = MustSign(GenPrivKeySecp256k1(), msg)
+			bad[i] = true
+		case 13:
+			// non-canonical S
+			sigEd := sig.(SignatureEd25519)
+			sigEd[63] |= 0xf0
+			sig = sigEd
+			bad[i] = true
+		}
+		bv.Add(priv.PubKey(), msg, sig)
+	}
+
+	ok, valid := bv.Verify()
+	assert.False(t, ok)
+	for i, v := range valid {
+		assert.Equal(t, !bad[i], v, "entry %d", i)
+	}
+}
+
+func TestBatchVerifierEmpty(t *testing.T) {
+	ok, valid := NewBatchVerifier().Verify()
+	assert.True(t, ok)
+	assert.Empty(t, valid)
+}
+
+// torsionSignature signs msg with priv, adding the small order point
+// tR to the nonce point R, and tA to the public key, which it returns.
+func torsionSignature(t *testing.T, priv PrivKeyEd25519, msg []byte, tR, tA *edwards25519.Point) (PubKeyEd25519, SignatureEd25519) {
+	h := sha512.Sum512(priv[:32])
+	a, err := edwards25519.NewScalar().SetBytesWithClamping(h[:32])
+	require.Nil(t, err, "%+v", err)
+	A := new(edwards25519.Point).ScalarBaseMult(a)
+	A.Add(A, tA)
+
+	r, err := edwards25519.NewScalar().SetUniformBytes(CRandBytes(64))
+	require.Nil(t, err, "%+v", err)
+	R := new(edwards25519.Point).ScalarBaseMult(r)
+	R.Add(R, tR)
+
+	kh := sha512.New()
+	kh.Write(R.Bytes())
+	kh.Write(A.Bytes())
+	kh.Write(msg)
+	k, err := edwards25519.NewScalar().SetUniformBytes(kh.Sum(nil))
+	require.Nil(t, err, "%+v", err)
+	S := edwards25519.NewScalar().MultiplyAdd(k, a, r)
+
+	var pub PubKeyEd25519
+	var sig SignatureEd25519
+	copy(pub[:], A.Bytes())
+	copy(sig[:32], R.Bytes())
+	copy(sig[32:], S.Bytes())
+	return pub, sig
+}
+
+// batchResult verifies the entries in one batch, and returns the
+// validity of the first one, and whether the whole batch passed.
+func batchResult(pub PubKey, msg []byte, sig Signature, others ...batchEntry) (bool, bool) {
+	bv := NewBatchVerifier()
+	bv.Add(pub, msg, sig)
+	for _, e := range others {
+		bv.Add(e.pubKey, e.msg, e.sig)
+	}
+	ok, valid := bv.Verify()
+	return valid[0], ok
+}
+
+func honestEntries(n int) []batchEntry {
+	var entries []batchEntry
+	for i := 0; i < n; i++ {
+		priv := GenPrivKeyEd25519()
+		msg := CRandBytes(32)
+		entries = append(entries, batchEntry{priv.PubKey(), msg, MustSign(priv, msg)})
+	}
+	return entries
+}
+
+// The batch and VerifyBytes only disagree on signatures for which
+// [s]B - R - [k]A is a small order point, when the batch passes.
+func TestBatchVerifierTorsion(t *testing.T) {
+	// a point of order 8
+	bz, _ := hex.DecodeString("26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc05")
+	t8, err := new(edwards25519.Point).SetBytes(bz)
+	require.Nil(t, err, "%+v", err)
+	require.Equal(t, 0, t8.Equal(edwards25519.NewIdentityPoint()))
+	require.Equal(t, 1, new(edwards25519.Point).MultByCofactor(t8).Equal(edwards25519.NewIdentityPoint()))
+	id := edwards25519.NewIdentityPoint()
+
+	for _, tc := range []struct {
+		name   string
+		tR, tA *edwards25519.Point
+	}{
+		{"torsion in R", t8, id},
+		{"torsion in A", id, t8},
+		{"torsion in R and A", t8, t8},
+	} {
+		priv := GenPrivKeyEd25519()
+		msg := CRandBytes(32)
+		pub, sig := torsionSignature(t, priv, msg, tc.tR, tc.tA)
+		pubBytes, sigBytes := [32]byte(pub), [64]byte(sig)
+		single := pub.VerifyBytes(msg, sig)
+		assert.Equal(t, ed25519.Verify(&pubBytes, msg, &sigBytes), single, tc.name)
+		if tc.tA == id {
+			// [s]B - R - [k]A is -T8
+			assert.False(t, single, tc.name)
+		}
+
+		// the cofactored batch accepts it, alone or among honest
+		// signatures
+		valid, ok := batchResult(pub, msg, sig)
+		assert.True(t, valid && ok, tc.name)
+		valid, ok = batchResult(pub, msg, sig, honestEntries(3)...)
+		assert.True(t, valid && ok, tc.name)
+
+		// a failed batch rechecks it like VerifyBytes
+		bad := honestEntries(1)
+		bad[0].msg = CRandBytes(32)
+		valid, ok = batchResult(pub, msg, sig, append(honestEntries(2), bad...)...)
+		assert.False(t, ok, tc.name)
+		assert.Equal(t, single, valid, tc.name)
+	}
+}
+
+// A non-canonical s is accepted by VerifyBytes, so the batch must leave
+// it to VerifyBytes too.
+func TestBatchVerifierNonCanonicalS(t *testing.T) {
+	priv := GenPrivKeyEd25519()
+	msg := CRandBytes(32)
+	sig := MustSign(priv, msg).(SignatureEd25519)
+
+	// s + l, little endian
+	l, _ := new(big.Int).SetString("7237005577332262213973186563042994240857116359379907606001950938285454250989", 10)
+	var le [32]byte
+	for i := range le {
+		le[31-i] = sig[32+i]
+	}
+	sl := new(big.Int).Add(new(big.Int).SetBytes(le[:]), l).FillBytes(le[:])
+	for i := range le {
+		sig[32+i] = sl[31-i]
+	}
+
+	single := priv.PubKey().VerifyBytes(msg, sig)
+	assert.True(t, single, "VerifyBytes doesn't reduce s")
+	valid, ok := batchResult(priv.PubKey(), msg, sig, honestEntries(3)...)
+	assert.Equal(t, single, valid)
+	assert.Equal(t, single, ok)
+}
+
+func benchmarkSignatures(b *testing.B, n int) (pubs []PubKey, msgs [][]byte, sigs []Signature) {
+	for i := 0; i < n; i++ {
+		priv := GenPrivKeyEd25519()
+		msg := CRandBytes(128)
+		pubs = append(pubs, priv.PubKey())
+		msgs = append(msgs, msg)
+		sigs = append(sigs, MustSign(priv, msg))
+	}
+	return
+}
+
+func BenchmarkVerifyEd25519Batch(b *testing.B) {
+	for _, n := range []int{1, 8, 64, 1024} {
+		pubs, msgs, sigs := benchmarkSignatures(b, n)
+		b.Run(fmt.Sprintf("batch-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bv := NewBatchVerifier()
+				for j := 0; j < n; j++ {
+					bv.Add(pubs[j], msgs[j], sigs[j])
+				}
+				if ok, _ := bv.Verify(); !ok {
+					b.Fatal("batch did not verify")
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("single-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < n; j++ {
+					if !pubs[j].VerifyBytes(msgs[j], sigs[j]) {
+						b.Fatal("signature did not verify")
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package crypto
+
+import (
+	"github.com/tendermint/go-crypto/bech32"
+)
+
+// Addresses are encoded with Bech32, like most chains do for their
+// fixed size addresses. Public keys and signatures are encoded with
+// Bech32m, which is the safer choice for variable length data. In all
+// cases the caller chooses the human readable prefix (hrp).
+
+// AddressToBech32 returns the Bech32 encoding of addr.
+func AddressToBech32(hrp string, addr Address) (string, error) {
+	return bech32.Encode(hrp, addr)
+}
+
+// AddressFromBech32 decodes a Bech32 address with the given prefix.
+func AddressFromBech32(hrp, s string) (Address, error) {
+	bz, err := bech32.DecodeWith(bech32.Bech32, hrp, s)
+	if err != nil {
+		return nil, err
+	}
+	return Address(bz), nil
+}
+
+// PubKeyToBech32 returns the Bech32m encoding of pub.Bytes().
+func PubKeyToBech32(hrp string, pub PubKey) (string, error) {
+	return bech32.EncodeM(hrp, pub.Bytes())
+}
+
+// PubKeyFromBech32 decodes a Bech32m public key with the given prefix.
+func PubKeyFromBech32(hrp, s string) (PubKey, error) {
+	bz, err := bech32.DecodeWith(bech32.Bech32m, hrp, s)
+	if err != nil {
+		return nil, err
+	}
+	return PubKeyFromBytes(bz)
+}
+
+// SignatureToBech32 returns the Bech32m encoding of sig.Bytes().
+func SignatureToBech32(hrp string, sig Signature) (string, error) {
+	return bech32.EncodeM(hrp, sig.Bytes())
+}
+
+// SignatureFromBech32 decodes a Bech32m signature with the given prefix.
+func SignatureFromBech32(hrp, s string) (Signature, error) {
+	bz, err := bech32.DecodeWith(bech32.Bech32m, hrp, s)
+	if err != nil {
+		return nil, err
+	}
+	return SignatureFromBytes(bz)
+}
@@ -0,0 +1,302 @@
+// Package bech32 implements the Bech32 (BIP-173) and Bech32m (BIP-350)
+// encodings of binary data with a human readable prefix (HRP).
+//
+// Unlike BIP-173, the length of a string is not limited to 90
+// characters, so that public keys and signatures can be encoded too.
+// The checksum is only guaranteed to detect up to 4 errors in strings
+// of up to 89 characters, which covers addresses.
+package bech32
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Encoding selects the checksum constant.
+type Encoding int
+
+const (
+	// Bech32 is the original encoding of BIP-173.
+	Bech32 Encoding = iota + 1
+	// Bech32m is the encoding of BIP-350. Prefer it for data whose
+	// length varies, where Bech32 can miss inserted or deleted 'q's
+	// in front of a final 'p'.
+	Bech32m
+)
+
+func (enc Encoding) String() string {
+	switch enc {
+	case Bech32:
+		return "bech32"
+	case Bech32m:
+		return "bech32m"
+	default:
+		return fmt.Sprintf("Encoding(%d)", int(enc))
+	}
+}
+
+func (enc Encoding) constant() uint32 {
+	if enc == Bech32m {
+		return 0x2bc830a3
+	}
+	return 1
+}
+
+// MaxLength is the maximum length of an encoded string.
+const MaxLength = 1023
+
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var charsetRev [128]int8
+
+func init() {
+	for i := range charsetRev {
+		charsetRev[i] = -1
+	}
+	for i, c := range charset {
+		charsetRev[c] = int8(i)
+	}
+}
+
+// Error is returned by Decode for malformed strings.
+// Positions holds the indexes of the offending characters within the
+// input, when they can be located.
+type Error struct {
+	Reason    string
+	Positions []int
+}
+
+func (err *Error) Error() string {
+	if len(err.Positions) == 0 {
+		return "bech32: " + err.Reason
+	}
+	return fmt.Sprintf("bech32: %s at position %v", err.Reason, err.Positions)
+}
+
+//-------------------------------------
+
+// Encode returns the Bech32 encoding of data with the given HRP.
+func Encode(hrp string, data []byte) (string, error) {
+	return EncodeWith(Bech32, hrp, data)
+}
+
+// EncodeM returns the Bech32m encoding of data with the given HRP.
+func EncodeM(hrp string, data []byte) (string, error) {
+	return EncodeWith(Bech32m, hrp, data)
+}
+
+// EncodeWith encodes data with the given encoding and HRP.
+// The HRP is lowercased, and so is the result.
+func EncodeWith(enc Encoding, hrp string, data []byte) (string, error) {
+	if enc != Bech32 && enc != Bech32m {
+		return "", fmt.Errorf("bech32: unknown encoding %v", enc)
+	}
+	if err := checkHRP(hrp); err != nil {
+		return "", err
+	}
+	hrp = strings.ToLower(hrp)
+	values, err := ConvertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	if len(hrp)+1+len(values)+6 > MaxLength {
+		return "", fmt.Errorf("bech32: data too long to encode")
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(hrp) + 1 + len(values) + 6)
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range values {
+		sb.WriteByte(charset[v])
+	}
+	for _, v := range createChecksum(enc, hrp, values) {
+		sb.WriteByte(charset[v])
+	}
+	return sb.String(), nil
+}
+
+// Decode decodes a Bech32 or Bech32m string and returns its HRP (in
+// lower case), data and encoding. Errors in the string are reported
+// as an *Error.
+func Decode(s string) (hrp string, data []byte, enc Encoding, err error) {
+	if len(s) > MaxLength {
+		return "", nil, 0, &Error{Reason: "string too long"}
+	}
+	lower, upper := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 33 || c > 126 {
+			return "", nil, 0, &Error{Reason: "invalid character", Positions: []int{i}}
+		}
+		if c >= 'a' && c <= 'z' {
+			lower = true
+		} else if c >= 'A' && c <= 'Z' {
+			upper = true
+		}
+	}
+	if lower && upper {
+		return "", nil, 0, &Error{Reason: "mixed case"}
+	}
+	s = strings.ToLower(s)
+
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 {
+		return "", nil, 0, &Error{Reason: "missing human readable part"}
+	}
+	if sep+7 > len(s) {
+		return "", nil, 0, &Error{Reason: "checksum too short"}
+	}
+	hrp = s[:sep]
+	values := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		v := charsetRev[s[i]]
+		if v < 0 {
+			return "", nil, 0, &Error{Reason: "invalid data character", Positions: []int{i}}
+		}
+		values = append(values, byte(v))
+	}
+
+	switch polymod(hrp, values) {
+	case Bech32.constant():
+		enc = Bech32
+	case Bech32m.constant():
+		enc = Bech32m
+	default:
+		return "", nil, 0, &Error{Reason: "invalid checksum", Positions: locateError(hrp, values, sep+1)}
+	}
+
+	data, err = ConvertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	return hrp, data, enc, nil
+}
+
+// DecodeWith is like Decode, but also checks the HRP and encoding.
+func DecodeWith(enc Encoding, hrp, s string) ([]byte, error) {
+	gotHRP, data, gotEnc, err := Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if gotHRP != strings.ToLower(hrp) {
+		return nil, fmt.Errorf("bech32: expected prefix %q, got %q", hrp, gotHRP)
+	}
+	if gotEnc != enc {
+		return nil, fmt.Errorf("bech32: expected %v, got %v", enc, gotEnc)
+	}
+	return data, nil
+}
+
+func checkHRP(hrp string) error {
+	if len(hrp) == 0 {
+		return fmt.Errorf("bech32: empty human readable part")
+	}
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return fmt.Errorf("bech32: invalid character in human readable part at position %d", i)
+		}
+	}
+	if strings.ToLower(hrp) != hrp && strings.ToUpper(hrp) != hrp {
+		return fmt.Errorf("bech32: mixed case human readable part")
+	}
+	return nil
+}
+
+//-------------------------------------
+
+// ConvertBits regroups data from fromBits to toBits wide groups.
+// With pad, the last group is zero padded, otherwise a non-zero
+// or oversized padding is an error.
+func ConvertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	acc, bits := uint32(0), uint(0)
+	maxv := uint32(1)<<toBits - 1
+	out := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, b := range data {
+		if uint32(b)>>fromBits != 0 {
+			return nil, fmt.Errorf("bech32: invalid data range %d", b)
+		}
+		acc = acc<<fromBits | uint32(b)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(toBits-bits)&maxv))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxv != 0 {
+		return nil, fmt.Errorf("bech32: invalid padding")
+	}
+	return out, nil
+}
+
+var generator = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+func polymodStep(chk uint32, v byte) uint32 {
+	top := chk >> 25
+	chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+	for i := 0; i < 5; i++ {
+		if top>>uint(i)&1 == 1 {
+			chk ^= generator[i]
+		}
+	}
+	return chk
+}
+
+func polymod(hrp string, values []byte) uint32 {
+	chk := uint32(1)
+	for i := 0; i < len(hrp); i++ {
+		chk = polymodStep(chk, hrp[i]>>5)
+	}
+	chk = polymodStep(chk, 0)
+	for i := 0; i < len(hrp); i++ {
+		chk = polymodStep(chk, hrp[i]&31)
+	}
+	for _, v := range values {
+		chk = polymodStep(chk, v)
+	}
+	return chk
+}
+
+func createChecksum(enc Encoding, hrp string, values []byte) []byte {
+	chk := polymod(hrp, append(append([]byte{}, values...), 0, 0, 0, 0, 0, 0)) ^ enc.constant()
+	out := make([]byte, 6)
+	for i := range out {
+		out[i] = byte(chk >> uint(5*(5-i)) & 31)
+	}
+	return out
+}
+
+// locateError returns the position of a single substituted character
+// that explains the bad checksum, or nil if there is none. offset is
+// the position of values[0] within the input string.
+// Long strings are not searched, as the checksum can't pinpoint
+// errors in them reliably.
+func locateError(hrp string, values []byte, offset int) []int {
+	if len(hrp)+1+len(values) > 90 {
+		return nil
+	}
+	var positions []int
+	candidate := make([]byte, len(values))
+	for i := range values {
+		copy(candidate, values)
+		for v := byte(0); v < 32; v++ {
+			if v == values[i] {
+				continue
+			}
+			candidate[i] = v
+			if chk := polymod(hrp, candidate); chk == Bech32.constant() || chk == Bech32m.constant() {
+				positions = append(positions, offset+i)
+				break
+			}
+		}
+	}
+	// Only a unique correction pinpoints the typo.
+	if len(positions) != 1 {
+		return nil
+	}
+	return positions
+}
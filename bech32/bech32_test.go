@@ -0,0 +1,165 @@
+package bech32
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidStrings(t *testing.T) {
+	// BIP-173 and BIP-350 test vectors
+	cases := []struct {
+		s   string
+		enc Encoding
+	}{
+		{"A12UEL5L", Bech32},
+		{"a12uel5l", Bech32},
+		{"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs", Bech32},
+		{"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", Bech32},
+		{"11" + strings.Repeat("q", 82) + "c8247j", Bech32},
+		{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", Bech32},
+		{"?1ezyfcl", Bech32},
+		{"A1LQFN3A", Bech32m},
+		{"a1lqfn3a", Bech32m},
+		{"an83characterlonghumanreadablepartthatcontainsthetheexcludedcharactersbioandnumber11sg7hg6", Bech32m},
+		{"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx", Bech32m},
+		{"11" + strings.Repeat("l", 82) + "ludsr8", Bech32m},
+		{"split1checkupstagehandshakeupstreamerranterredcaperredlc445v", Bech32m},
+		{"?1v759aa", Bech32m},
+	}
+	for _, tc := range cases {
+		hrp, values, enc, err := decodeValues(tc.s)
+		require.Nil(t, err, "%s: %+v", tc.s, err)
+		assert.Equal(t, tc.enc, enc, tc.s)
+		// re-encode the 5 bit groups, which needn't be whole bytes
+		assert.Equal(t, strings.ToLower(tc.s), encodeValues(enc, hrp, values), tc.s)
+	}
+}
+
+func TestInvalidStrings(t *testing.T) {
+	cases := []string{
+		"\x201nwldj5",   // HRP character out of range
+		"\x7f1axkwrx",   // HRP character out of range
+		"pzry9x0s0muk",  // no separator
+		"1pzry9x0s0muk", // empty HRP
+		"x1b4n0q5v",     // invalid data character
+		"li1dgmt3",      // too short checksum
+		"de1lg7wt\xff",  // invalid character in checksum
+		"A1G7SGD8",      // checksum calculated with uppercase HRP
+		"10a06t8",       // empty HRP
+		"1qzzfhee",      // empty HRP
+		"a1QdG7sgd8",    // mixed case
+	}
+	for _, s := range cases {
+		_, _, _, err := Decode(s)
+		assert.NotNil(t, err, "%q", s)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	// BIP-173 P2WPKH example: witness version 0 and the program
+	program, _ := hex.DecodeString("751e76e8199196d454941c45d1b3a323f1433bd6")
+	values, err := ConvertBits(program, 8, 5, true)
+	require.Nil(t, err, "%+v", err)
+	values = append([]byte{0}, values...)
+	assert.Equal(t, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", encodeValues(Bech32, "bc", values))
+
+	for _, enc := range []Encoding{Bech32, Bech32m} {
+		for _, n := range []int{0, 1, 20, 33, 64, 100} {
+			data := make([]byte, n)
+			for i := range data {
+				data[i] = byte(i * 7)
+			}
+			s, err := EncodeWith(enc, "tm", data)
+			require.Nil(t, err, "%+v", err)
+			hrp, data2, enc2, err := Decode(s)
+			require.Nil(t, err, "%+v", err)
+			assert.Equal(t, "tm", hrp)
+			assert.Equal(t, enc, enc2)
+			assert.Equal(t, data, append([]byte{}, data2...))
+
+			// upper case strings decode just as well
+			_, data2, _, err = Decode(strings.ToUpper(s))
+			require.Nil(t, err, "%+v", err)
+			assert.Equal(t, data, append([]byte{}, data2...))
+		}
+	}
+}
+
+func TestDecodeWith(t *testing.T) {
+	s, err := EncodeM("tm", []byte("hello"))
+	require.Nil(t, err, "%+v", err)
+
+	data, err := DecodeWith(Bech32m, "tm", s)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, "hello", string(data))
+
+	_, err = DecodeWith(Bech32, "tm", s)
+	assert.NotNil(t, err)
+	_, err = DecodeWith(Bech32m, "cosmos", s)
+	assert.NotNil(t, err)
+}
+
+func TestErrorPositions(t *testing.T) {
+	s, err := Encode("tm", []byte("some address bytes"))
+	require.Nil(t, err, "%+v", err)
+
+	// a single typo is located, wherever it is
+	for _, pos := range []int{3, 10, len(s) - 8, len(s) - 1} {
+		typo := []byte(s)
+		if typo[pos] == 'q' {
+			typo[pos] = 'p'
+		} else {
+			typo[pos] = 'q'
+		}
+		_, _, _, err := Decode(string(typo))
+		require.NotNil(t, err)
+		bErr, ok := err.(*Error)
+		require.True(t, ok)
+		assert.Equal(t, []int{pos}, bErr.Positions, "typo at %d", pos)
+	}
+
+	// so are invalid characters
+	typo := []byte(s)
+	typo[5] = 'b'
+	_, _, _, err = Decode(string(typo))
+	require.NotNil(t, err)
+	assert.Equal(t, []int{5}, err.(*Error).Positions)
+}
+
+func decodeValues(s string) (string, []byte, Encoding, error) {
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	if _, _, _, err := Decode(s); err != nil {
+		// the vectors' data need not be whole bytes
+		if _, ok := err.(*Error); ok {
+			return "", nil, 0, err
+		}
+	}
+	hrp := s[:sep]
+	values := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		values = append(values, byte(charsetRev[s[i]]))
+	}
+	switch polymod(hrp, values) {
+	case Bech32.constant():
+		return hrp, values[:len(values)-6], Bech32, nil
+	case Bech32m.constant():
+		return hrp, values[:len(values)-6], Bech32m, nil
+	}
+	return "", nil, 0, &Error{Reason: "invalid checksum"}
+}
+
+func encodeValues(enc Encoding, hrp string, values []byte) string {
+	out := hrp + "1"
+	for _, v := range values {
+		out += string(charset[v])
+	}
+	for _, v := range createChecksum(enc, hrp, values) {
+		out += string(charset[v])
+	}
+	return out
+}
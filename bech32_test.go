@@ -0,0 +1,62 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/go-crypto/bech32"
+)
+
+func TestBech32Encodings(t *testing.T) {
+	privKeys := []PrivKey{GenPrivKeyEd25519(), GenPrivKeySecp256k1(), GenPrivKeySchnorr()}
+	for _, priv := range privKeys {
+		pub := priv.PubKey()
+
+		s, err := AddressToBech32("tm", pub.Address())
+		require.Nil(t, err, "%+v", err)
+		addr, err := AddressFromBech32("tm", s)
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, pub.Address(), addr)
+		_, err = AddressFromBech32("cosmos", s)
+		assert.NotNil(t, err)
+
+		s, err = PubKeyToBech32("tmpub", pub)
+		require.Nil(t, err, "%+v", err)
+		pub2, err := PubKeyFromBech32("tmpub", s)
+		require.Nil(t, err, "%+v", err)
+		assert.True(t, pub.Equals(pub2))
+
+		sig, err := priv.Sign([]byte("msg"))
+		require.Nil(t, err, "%+v", err)
+		s, err = SignatureToBech32("tmsig", sig)
+		require.Nil(t, err, "%+v", err)
+		sig2, err := SignatureFromBech32("tmsig", s)
+		require.Nil(t, err, "%+v", err)
+		assert.True(t, sig.Equals(sig2))
+	}
+}
+
+func TestBech32AddressTypo(t *testing.T) {
+	addr := GenPrivKeyEd25519().PubKey().Address()
+	s, err := AddressToBech32("tm", addr)
+	require.Nil(t, err, "%+v", err)
+
+	typo := []byte(s)
+	if typo[8] == 'x' {
+		typo[8] = 'y'
+	} else {
+		typo[8] = 'x'
+	}
+	_, err = AddressFromBech32("tm", string(typo))
+	require.NotNil(t, err)
+	bErr, ok := err.(*bech32.Error)
+	require.True(t, ok)
+	assert.Equal(t, []int{8}, bErr.Positions)
+
+	// a Bech32m string is not an address
+	s, err = bech32.EncodeM("tm", addr)
+	require.Nil(t, err, "%+v", err)
+	_, err = AddressFromBech32("tm", s)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,124 @@
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// CompactBitArray is a space efficient bit array, used to record
+// which members of a multisig have signed.
+// ExtraBitsStored is the number of bits used in the last byte of Elems.
+// It is always in the range [0, 8); zero means the last byte is full.
+type CompactBitArray struct {
+	ExtraBitsStored byte   `json:"extra_bits"`
+	Elems           []byte `json:"bits"`
+}
+
+// NewCompactBitArray returns a new compact bit array of the given size.
+// It returns nil if the number of bits is not positive.
+func NewCompactBitArray(bits int) *CompactBitArray {
+	if bits <= 0 {
+		return nil
+	}
+	return &CompactBitArray{
+		ExtraBitsStored: byte(bits % 8),
+		Elems:           make([]byte, (bits+7)/8),
+	}
+}
+
+// Size returns the number of bits in the bitarray.
+// It is zero for malformed bit arrays, see isValid.
+func (bA *CompactBitArray) Size() int {
+	if !bA.isValid() {
+		return 0
+	} else if bA.ExtraBitsStored == 0 {
+		return len(bA.Elems) * 8
+	}
+	return (len(bA.Elems)-1)*8 + int(bA.ExtraBitsStored)
+}
+
+// isValid returns false for nil, and for bit arrays decoded from bytes
+// whose ExtraBitsStored is out of range or doesn't fit Elems.
+func (bA *CompactBitArray) isValid() bool {
+	if bA == nil || bA.ExtraBitsStored >= 8 {
+		return false
+	}
+	return bA.ExtraBitsStored == 0 || len(bA.Elems) > 0
+}
+
+// GetIndex returns the bit at index i within the bit array.
+// It returns false if i is out of range.
+func (bA *CompactBitArray) GetIndex(i int) bool {
+	if bA == nil || i < 0 || i >= bA.Size() {
+		return false
+	}
+	return bA.Elems[i>>3]&(uint8(1)<<uint8(7-(i%8))) > 0
+}
+
+// SetIndex sets the bit at index i within the bit array.
+// It returns false if i is out of range.
+func (bA *CompactBitArray) SetIndex(i int, v bool) bool {
+	if bA == nil || i < 0 || i >= bA.Size() {
+		return false
+	}
+	if v {
+		bA.Elems[i>>3] |= (uint8(1) << uint8(7-(i%8)))
+	} else {
+		bA.Elems[i>>3] &= ^(uint8(1) << uint8(7-(i%8)))
+	}
+	return true
+}
+
+// NumTrueBitsBefore returns the number of bits set to true before the
+// given index. e.g. if bA = _XX__XX, NumTrueBitsBefore(4) = 2, since
+// there are two bits set to true before index 4.
+func (bA *CompactBitArray) NumTrueBitsBefore(index int) int {
+	numTrueValues := 0
+	for i := 0; i < index; i++ {
+		if bA.GetIndex(i) {
+			numTrueValues++
+		}
+	}
+	return numTrueValues
+}
+
+// Copy returns a copy of the provided bit array.
+func (bA *CompactBitArray) Copy() *CompactBitArray {
+	if bA == nil {
+		return nil
+	}
+	c := make([]byte, len(bA.Elems))
+	copy(c, bA.Elems)
+	return &CompactBitArray{
+		ExtraBitsStored: bA.ExtraBitsStored,
+		Elems:           c,
+	}
+}
+
+// Equals returns true if both bit arrays have the same size and bits.
+func (bA *CompactBitArray) Equals(other *CompactBitArray) bool {
+	if bA.Size() != other.Size() {
+		return false
+	}
+	if bA == nil || other == nil {
+		return bA == other
+	}
+	return bytes.Equal(bA.Elems, other.Elems)
+}
+
+// String returns a string representation of CompactBitArray: BA{<bit-string>},
+// where <bit-string> is a sequence of 'x' (1) and '_' (0).
+func (bA *CompactBitArray) String() string {
+	if bA == nil {
+		return "nil-BitArray"
+	}
+	bits := make([]byte, bA.Size())
+	for i := range bits {
+		if bA.GetIndex(i) {
+			bits[i] = 'x'
+		} else {
+			bits[i] = '_'
+		}
+	}
+	return fmt.Sprintf("BA{%v:%s}", bA.Size(), bits)
+}
@@ -0,0 +1,304 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	bls12381 "github.com/kilic/bls12-381"
+	. "github.com/tendermint/tmlibs/common"
+	"golang.org/x/crypto/hkdf"
+)
+
+// This file implements BLS signatures over the BLS12-381 curve, following
+// https://tools.ietf.org/html/draft-irtf-cfrg-bls-signature-04
+// with the proof of possession ciphersuite: public keys are in G1
+// (48 bytes compressed), signatures in G2 (96 bytes compressed).
+//
+// Aggregating public keys of the same message is only safe for keys
+// whose proof of possession has been checked, see VerifyPossession.
+
+const (
+	blsDomainSign = "BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"
+	blsDomainPoP  = "BLS_POP_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"
+)
+
+//-------------------------------------
+
+var _ PrivKey = PrivKeyBLS12381{}
+
+// Implements PrivKey.
+// Big endian scalar in [1, r).
+type PrivKeyBLS12381 [32]byte
+
+func (privKey PrivKeyBLS12381) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(privKey)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (privKey PrivKeyBLS12381) Sign(msg []byte) (Signature, error) {
+	sig, err := privKey.signWithDomain(msg, blsDomainSign)
+	if err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+func (privKey PrivKeyBLS12381) PubKey() PubKey {
+	g1 := bls12381.NewG1()
+	p := g1.MulScalarBig(g1.New(), g1.One(), new(big.Int).SetBytes(privKey[:]))
+	var pub PubKeyBLS12381
+	copy(pub[:], g1.ToCompressed(p))
+	return pub
+}
+
+// Equals - you probably don't need to use this.
+// Runs in constant time based on length of the keys.
+func (privKey PrivKeyBLS12381) Equals(other PrivKey) bool {
+	if otherBLS, ok := other.(PrivKeyBLS12381); ok {
+		return subtle.ConstantTimeCompare(privKey[:], otherBLS[:]) == 1
+	} else {
+		return false
+	}
+}
+
+// ProvePossession returns a proof that the holder of the public key
+// knows the private key, i.e. a signature of the public key itself
+// under a separate domain.
+func (privKey PrivKeyBLS12381) ProvePossession() (SignatureBLS12381, error) {
+	pub := privKey.PubKey().(PubKeyBLS12381)
+	return privKey.signWithDomain(pub[:], blsDomainPoP)
+}
+
+func (privKey PrivKeyBLS12381) signWithDomain(msg []byte, domain string) (SignatureBLS12381, error) {
+	var sig SignatureBLS12381
+	g2 := bls12381.NewG2()
+	h, err := g2.HashToCurve(msg, []byte(domain))
+	if err != nil {
+		return sig, err
+	}
+	p := g2.MulScalarBig(g2.New(), h, new(big.Int).SetBytes(privKey[:]))
+	copy(sig[:], g2.ToCompressed(p))
+	return sig, nil
+}
+
+func GenPrivKeyBLS12381() PrivKeyBLS12381 {
+	return blsKeyGen(CRandBytes(32))
+}
+
+// NOTE: secret should be the output of a KDF like bcrypt,
+// if it's derived from user input.
+func GenPrivKeyBLS12381FromSecret(secret []byte) PrivKeyBLS12381 {
+	return blsKeyGen(Sha256(secret))
+}
+
+// blsKeyGen implements KeyGen from the BLS signature draft.
+// ikm must be at least 32 bytes.
+func blsKeyGen(ikm []byte) PrivKeyBLS12381 {
+	order := bls12381.NewG1().Q()
+	salt := []byte("BLS-SIG-KEYGEN-SALT-")
+	// IKM || I2OSP(0, 1)
+	ikm = append(append([]byte{}, ikm...), 0)
+	sk := new(big.Int)
+	for sk.Sign() == 0 {
+		saltHash := sha256.Sum256(salt)
+		salt = saltHash[:]
+		// L = ceil((3 * ceil(log2(r))) / 16) = 48
+		okm := make([]byte, 48)
+		kdf := hkdf.New(sha256.New, ikm, salt, []byte{0, 48})
+		if _, err := io.ReadFull(kdf, okm); err != nil {
+			PanicSanity(err)
+		}
+		sk.SetBytes(okm)
+		sk.Mod(sk, order)
+	}
+	var privKey PrivKeyBLS12381
+	copy(privKey[:], intToBytes32(sk))
+	return privKey
+}
+
+//-------------------------------------
+
+var _ PubKey = PubKeyBLS12381{}
+
+// Implements PubKey.
+// Compressed G1 point.
+type PubKeyBLS12381 [48]byte
+
+func (pubKey PubKeyBLS12381) Address() Address {
+	return Address(Ripemd160(pubKey.Bytes()))
+}
+
+func (pubKey PubKeyBLS12381) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(pubKey)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (pubKey PubKeyBLS12381) VerifyBytes(msg []byte, sig_ Signature) bool {
+	// make sure we use the same algorithm to sign
+	sig, ok := sig_.(SignatureBLS12381)
+	if !ok {
+		return false
+	}
+	return blsVerify([]PubKeyBLS12381{pubKey}, [][]byte{msg}, sig, blsDomainSign)
+}
+
+// VerifyPossession checks a proof created by ProvePossession.
+func (pubKey PubKeyBLS12381) VerifyPossession(proof SignatureBLS12381) bool {
+	return blsVerify([]PubKeyBLS12381{pubKey}, [][]byte{pubKey[:]}, proof, blsDomainPoP)
+}
+
+func (pubKey PubKeyBLS12381) String() string {
+	return fmt.Sprintf("PubKeyBLS12381{%X}", pubKey[:])
+}
+
+func (pubKey PubKeyBLS12381) Equals(other PubKey) bool {
+	if otherBLS, ok := other.(PubKeyBLS12381); ok {
+		return bytes.Equal(pubKey[:], otherBLS[:])
+	} else {
+		return false
+	}
+}
+
+//-------------------------------------
+
+var _ Signature = SignatureBLS12381{}
+
+// Implements Signature.
+// Compressed G2 point.
+type SignatureBLS12381 [96]byte
+
+func (sig SignatureBLS12381) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(sig)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (sig SignatureBLS12381) IsZero() bool { return len(sig) == 0 }
+
+func (sig SignatureBLS12381) String() string { return fmt.Sprintf("/%X.../", Fingerprint(sig[:])) }
+
+func (sig SignatureBLS12381) Equals(other Signature) bool {
+	if otherBLS, ok := other.(SignatureBLS12381); ok {
+		return bytes.Equal(sig[:], otherBLS[:])
+	} else {
+		return false
+	}
+}
+
+//-------------------------------------
+// Aggregation
+
+// AggregateSignatures combines BLS signatures into a single one,
+// which can be checked with VerifyAggregate.
+func AggregateSignatures(sigs []Signature) (SignatureBLS12381, error) {
+	var agg SignatureBLS12381
+	if len(sigs) == 0 {
+		return agg, errors.New("No signatures to aggregate")
+	}
+	g2 := bls12381.NewG2()
+	sum := g2.Zero()
+	for i, sig_ := range sigs {
+		sig, ok := sig_.(SignatureBLS12381)
+		if !ok {
+			return agg, fmt.Errorf("Signature %d is not a BLS12-381 signature", i)
+		}
+		p, err := g2.FromCompressed(sig[:])
+		if err != nil {
+			return agg, fmt.Errorf("Signature %d: %v", i, err)
+		}
+		g2.Add(sum, sum, p)
+	}
+	copy(agg[:], g2.ToCompressed(sum))
+	return agg, nil
+}
+
+// AggregatePubKeys combines BLS public keys into a single one, which
+// verifies the aggregate signature of a message signed by all of them.
+// NOTE: Only aggregate keys whose proof of possession has been verified,
+// otherwise a rogue key can forge the aggregate.
+func AggregatePubKeys(pubKeys []PubKey) (PubKeyBLS12381, error) {
+	var agg PubKeyBLS12381
+	if len(pubKeys) == 0 {
+		return agg, errors.New("No public keys to aggregate")
+	}
+	g1 := bls12381.NewG1()
+	sum := g1.Zero()
+	for i, pubKey_ := range pubKeys {
+		pubKey, ok := pubKey_.(PubKeyBLS12381)
+		if !ok {
+			return agg, fmt.Errorf("PubKey %d is not a BLS12-381 public key", i)
+		}
+		p, err := g1.FromCompressed(pubKey[:])
+		if err != nil {
+			return agg, fmt.Errorf("PubKey %d: %v", i, err)
+		}
+		g1.Add(sum, sum, p)
+	}
+	copy(agg[:], g1.ToCompressed(sum))
+	return agg, nil
+}
+
+// VerifyAggregate checks an aggregate signature created by
+// AggregateSignatures.
+// If msgs holds a single message, all keys are expected to have signed
+// that same message. Otherwise pubKeys[i] must have signed msgs[i].
+// Keys must have a verified proof of possession, see AggregatePubKeys.
+func VerifyAggregate(pubKeys []PubKey, msgs [][]byte, sig_ Signature) bool {
+	sig, ok := sig_.(SignatureBLS12381)
+	if !ok || len(pubKeys) == 0 {
+		return false
+	}
+	if len(msgs) == 1 {
+		agg, err := AggregatePubKeys(pubKeys)
+		if err != nil {
+			return false
+		}
+		return agg.VerifyBytes(msgs[0], sig)
+	}
+	if len(msgs) != len(pubKeys) {
+		return false
+	}
+	keys := make([]PubKeyBLS12381, len(pubKeys))
+	for i, pubKey_ := range pubKeys {
+		if keys[i], ok = pubKey_.(PubKeyBLS12381); !ok {
+			return false
+		}
+	}
+	return blsVerify(keys, msgs, sig, blsDomainSign)
+}
+
+// blsVerify checks e(G1, sig) == e(pubKeys[0], H(msgs[0])) * ... * e(pubKeys[n], H(msgs[n])).
+func blsVerify(pubKeys []PubKeyBLS12381, msgs [][]byte, sig SignatureBLS12381, domain string) bool {
+	g1, g2 := bls12381.NewG1(), bls12381.NewG2()
+	s, err := g2.FromCompressed(sig[:])
+	if err != nil {
+		return false
+	}
+	engine := bls12381.NewEngine()
+	for i, pubKey := range pubKeys {
+		p, err := g1.FromCompressed(pubKey[:])
+		if err != nil || g1.IsZero(p) {
+			return false
+		}
+		h, err := g2.HashToCurve(msgs[i], []byte(domain))
+		if err != nil {
+			return false
+		}
+		engine.AddPair(p, h)
+	}
+	engine.AddPairInv(g1.One(), s)
+	return engine.Check()
+}
@@ -0,0 +1,233 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndValidateBLS12381(t *testing.T) {
+	privKey := GenPrivKeyBLS12381()
+	pubKey := privKey.PubKey()
+
+	msg := CRandBytes(128)
+	sig, err := privKey.Sign(msg)
+	require.Nil(t, err, "%+v", err)
+
+	assert.True(t, pubKey.VerifyBytes(msg, sig))
+	assert.False(t, pubKey.VerifyBytes(CRandBytes(128), sig))
+	assert.False(t, GenPrivKeyBLS12381().PubKey().VerifyBytes(msg, sig))
+
+	// Mutate the signature, just one bit.
+	sigBLS := sig.(SignatureBLS12381)
+	sigBLS[7] ^= byte(0x01)
+	sig = sigBLS
+
+	assert.False(t, pubKey.VerifyBytes(msg, sig))
+}
+
+func TestBLS12381FromSecret(t *testing.T) {
+	secret := []byte("a very secret secret")
+	privKey := GenPrivKeyBLS12381FromSecret(secret)
+	assert.Equal(t, privKey, GenPrivKeyBLS12381FromSecret(secret))
+	assert.NotEqual(t, privKey, GenPrivKeyBLS12381FromSecret([]byte("another secret")))
+}
+
+func TestBLS12381ProofOfPossession(t *testing.T) {
+	privKey := GenPrivKeyBLS12381()
+	pubKey := privKey.PubKey().(PubKeyBLS12381)
+
+	proof, err := privKey.ProvePossession()
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, pubKey.VerifyPossession(proof))
+
+	// the proof does not carry over to another key
+	other := GenPrivKeyBLS12381().PubKey().(PubKeyBLS12381)
+	assert.False(t, other.VerifyPossession(proof))
+
+	// a proof is not a signature of the public key and vice versa
+	assert.False(t, pubKey.VerifyBytes(pubKey[:], proof))
+	assert.False(t, pubKey.VerifyPossession(MustSign(privKey, pubKey[:]).(SignatureBLS12381)))
+}
+
+func TestBLS12381AggregateSameMessage(t *testing.T) {
+	msg := []byte("block hash")
+	var pubKeys []PubKey
+	var sigs []Signature
+	for i := 0; i < 8; i++ {
+		privKey := GenPrivKeyBLS12381()
+		pubKeys = append(pubKeys, privKey.PubKey())
+		sigs = append(sigs, MustSign(privKey, msg))
+	}
+
+	aggSig, err := AggregateSignatures(sigs)
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, VerifyAggregate(pubKeys, [][]byte{msg}, aggSig))
+	assert.False(t, VerifyAggregate(pubKeys[1:], [][]byte{msg}, aggSig))
+	assert.False(t, VerifyAggregate(pubKeys, [][]byte{[]byte("other block")}, aggSig))
+
+	aggPub, err := AggregatePubKeys(pubKeys)
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, aggPub.VerifyBytes(msg, aggSig))
+}
+
+func TestBLS12381AggregateDistinctMessages(t *testing.T) {
+	var pubKeys []PubKey
+	var msgs [][]byte
+	var sigs []Signature
+	for i := 0; i < 4; i++ {
+		privKey := GenPrivKeyBLS12381()
+		msg := CRandBytes(32)
+		pubKeys = append(pubKeys, privKey.PubKey())
+		msgs = append(msgs, msg)
+		sigs = append(sigs, MustSign(privKey, msg))
+	}
+
+	aggSig, err := AggregateSignatures(sigs)
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, VerifyAggregate(pubKeys, msgs, aggSig))
+
+	// swapping messages between signers fails
+	msgs[0], msgs[1] = msgs[1], msgs[0]
+	assert.False(t, VerifyAggregate(pubKeys, msgs, aggSig))
+	assert.False(t, VerifyAggregate(pubKeys, msgs[1:], aggSig))
+}
+
+func TestBLS12381AggregateErrors(t *testing.T) {
+	_, err := AggregateSignatures(nil)
+	assert.NotNil(t, err)
+	_, err = AggregatePubKeys(nil)
+	assert.NotNil(t, err)
+
+	msg := []byte("msg")
+	_, err = AggregateSignatures([]Signature{MustSign(GenPrivKeyEd25519(), msg)})
+	assert.NotNil(t, err)
+	_, err = AggregatePubKeys([]PubKey{GenPrivKeyEd25519().PubKey()})
+	assert.NotNil(t, err)
+}
+
+func TestBLS12381Encodings(t *testing.T) {
+	privKey := GenPrivKeyBLS12381()
+
+	var priv2 PrivKey
+	checkAminoBinary(t, privKey, &priv2, 37)
+	assert.EqualValues(t, privKey, priv2)
+
+	var pub2 PubKey
+	checkAminoBinary(t, privKey.PubKey(), &pub2, 53)
+	assert.EqualValues(t, privKey.PubKey(), pub2)
+
+	var sig2 Signature
+	sig := MustSign(privKey, []byte("something"))
+	checkAminoBinary(t, sig, &sig2, 101)
+	assert.EqualValues(t, sig, sig2)
+}
+
+// Known answer tests from the Ethereum consensus BLS test suite,
+// https://github.com/ethereum/bls12-381-tests, which uses the same
+// proof of possession ciphersuite. Each key signs the 32 byte messages
+// 0x00..00, 0x56..56 and 0xab..ab.
+var blsVectorKeys = []struct {
+	priv, pub string
+	sigs      [3]string
+	// pop is our own proof of possession of the key. The suite has no
+	// PoP vectors; it only differs from signing by its domain.
+	pop string
+}{
+	{
+		priv: "263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3",
+		pub:  "a491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a",
+		sigs: [3]string{
+			"b6ed936746e01f8ecf281f020953fbf1f01debd5657c4a383940b020b26507f6076334f91e2366c96e9ab279fb5158090352ea1c5b0c9274504f4f0e7053af24802e51e4568d164fe986834f41e55c8e850ce1f98458c0cfc9ab380b55285a55",
+			"882730e5d03f6b42c3abc26d3372625034e1d871b65a8a6b900a56dae22da98abbe1b68f85e49fe7652a55ec3d0591c20767677e33e5cbb1207315c41a9ac03be39c2e7668edc043d6cb1d9fd93033caa8a1c5b0e84bedaeb6c64972503a43eb",
+			"91347bccf740d859038fcdcaf233eeceb2a436bcaaee9b2aa3bfb70efe29dfb2677562ccbea1c8e061fb9971b0753c240622fab78489ce96768259fc01360346da5b9f579e5da0d941e4c6ba18a0e64906082375394f337fa1af2b7127b0d121",
+		},
+		pop: "b803eb0ed93ea10224a73b6b9c725796be9f5fefd215ef7a5b97234cc956cf6870db6127b7e4d824ec62276078e787db05584ce1adbf076bc0808ca0f15b73d59060254b25393d95dfc7abe3cda566842aaedf50bbb062aae1bbb6ef3b1f77e1",
+	},
+	{
+		priv: "47b8192d77bf871b62e87859d653922725724a5c031afeabc60bcef5ff665138",
+		pub:  "b301803f8b5ac4a1133581fc676dfedc60d891dd5fa99028805e5ea5b08d3491af75d0707adab3b70c6a6a580217bf81",
+		sigs: [3]string{
+			"b23c46be3a001c63ca711f87a005c200cc550b9429d5f4eb38d74322144f1b63926da3388979e5321012fb1a0526bcd100b5ef5fe72628ce4cd5e904aeaa3279527843fae5ca9ca675f4f51ed8f83bbf7155da9ecc9663100a885d5dc6df96d9",
+			"af1390c3c47acdb37131a51216da683c509fce0e954328a59f93aebda7e4ff974ba208d9a4a2a2389f892a9d418d618418dd7f7a6bc7aa0da999a9d3a5b815bc085e14fd001f6a1948768a3f4afefc8b8240dda329f984cb345c6363272ba4fe",
+			"9674e2228034527f4c083206032b020310face156d4a4685e2fcaec2f6f3665aa635d90347b6ce124eb879266b1e801d185de36a0a289b85e9039662634f2eea1e02e670bc7ab849d006a70b2f93b84597558a05b879c8d445f387a5d5b653df",
+		},
+		pop: "88bb31b27eae23038e14f9d9d1b628a39f5881b5278c3c6f0249f81ba0deb1f68aa5f8847854d6554051aa810fdf1cdb02df4af7a5647b1aa4afb60ec6d446ee17af24a8a50876ffdaf9bf475038ec5f8ebeda1c1c6a3220293e23b13a9a5d26",
+	},
+	{
+		priv: "328388aff0d4a5b7dc9205abd374e7e98f3cd9f3418edb4eafda5fb16473d216",
+		pub:  "b53d21a4cfd562c469cc81514d4ce5a6b577d8403d32a394dc265dd190b47fa9f829fdd7963afdf972e5e77854051f6f",
+		sigs: [3]string{
+			"948a7cb99f76d616c2c564ce9bf4a519f1bea6b0a624a02276443c245854219fabb8d4ce061d255af5330b078d5380681751aa7053da2c98bae898edc218c75f07e24d8802a17cd1f6833b71e58f5eb5b94208b4d0bb3848cecb075ea21be115",
+			"a4efa926610b8bd1c8330c918b7a5e9bf374e53435ef8b7ec186abf62e1b1f65aeaaeb365677ac1d1172a1f5b44b4e6d022c252c58486c0a759fbdc7de15a756acc4d343064035667a594b4c2a6f0b0b421975977f297dba63ee2f63ffe47bb6",
+			"ae82747ddeefe4fd64cf9cedb9b04ae3e8a43420cd255e3c7cd06a8d88b7c7f8638543719981c5d16fa3527c468c25f0026704a6951bde891360c7e8d12ddee0559004ccdbe6046b55bae1b257ee97f7cdb955773d7cf29adf3ccbb9975e4eb9",
+		},
+		pop: "88873ea58f5017a33facc9bf04efaf5e2f34f7bc9ce564d0481dd469326c04ef43552f50e99de8a13315dcd37a4fb9ef036d1a54e5febf5d20b6aa488f3e3c917e6a96ce6461f609ec7e0a1fd8950380922e46c3654fa7542436603f833462da",
+	},
+}
+
+// blsVectorMsgs are the messages of blsVectorKeys, and blsVectorAggs
+// the aggregates of the signatures of each message.
+var (
+	blsVectorMsgs = [3][]byte{
+		bytes.Repeat([]byte{0x00}, 32),
+		bytes.Repeat([]byte{0x56}, 32),
+		bytes.Repeat([]byte{0xab}, 32),
+	}
+	blsVectorAggs = [3]string{
+		"9683b3e6701f9a4b706709577963110043af78a5b41991b998475a3d3fd62abf35ce03b33908418efc95a058494a8ae504354b9f626231f6b3f3c849dfdeaf5017c4780e2aee1850ceaf4b4d9ce70971a3d2cfcd97b7e5ecf6759f8da5f76d31",
+		"ad38fc73846583b08d110d16ab1d026c6ea77ac2071e8ae832f56ac0cbcdeb9f5678ba5ce42bd8dce334cc47b5abcba40a58f7f1f80ab304193eb98836cc14d8183ec14cc77de0f80c4ffd49e168927a968b5cdaa4cf46b9805be84ad7efa77b",
+		"9712c3edd73a209c742b8250759db12549b3eaf43b5ca61376d9f30e2747dbcf842d8b2ac0901d2a093713e20284a7670fcf6954e9ab93de991bb9b313e664785a075fc285806fa5224c82bde146561b446ccfc706a64b8579513cfc4ff1d930",
+	}
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	bz, err := hex.DecodeString(s)
+	require.Nil(t, err, "%+v", err)
+	return bz
+}
+
+func TestBLS12381Vectors(t *testing.T) {
+	var pubKeys []PubKey
+	var sigs [3][]Signature
+	for i, v := range blsVectorKeys {
+		var privKey PrivKeyBLS12381
+		copy(privKey[:], mustDecodeHex(t, v.priv))
+		pubKey := privKey.PubKey().(PubKeyBLS12381)
+		assert.Equal(t, v.pub, hex.EncodeToString(pubKey[:]), "key %d", i)
+		pubKeys = append(pubKeys, pubKey)
+
+		for j, msg := range blsVectorMsgs {
+			sig, err := privKey.Sign(msg)
+			require.Nil(t, err, "%+v", err)
+			sigBLS := sig.(SignatureBLS12381)
+			assert.Equal(t, v.sigs[j], hex.EncodeToString(sigBLS[:]), "key %d, msg %d", i, j)
+			assert.True(t, pubKey.VerifyBytes(msg, sig), "key %d, msg %d", i, j)
+			assert.False(t, pubKey.VerifyBytes(blsVectorMsgs[(j+1)%3], sig), "key %d, msg %d", i, j)
+			sigs[j] = append(sigs[j], sig)
+		}
+
+		proof, err := privKey.ProvePossession()
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, v.pop, hex.EncodeToString(proof[:]), "key %d", i)
+		assert.True(t, pubKey.VerifyPossession(proof), "key %d", i)
+	}
+
+	for j, msg := range blsVectorMsgs {
+		agg, err := AggregateSignatures(sigs[j])
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, blsVectorAggs[j], hex.EncodeToString(agg[:]), "msg %d", j)
+		assert.True(t, VerifyAggregate(pubKeys, [][]byte{msg}, agg), "msg %d", j)
+		assert.False(t, VerifyAggregate(pubKeys[1:], [][]byte{msg}, agg), "msg %d", j)
+	}
+
+	// the key and signature at infinity never verify
+	var pubInf PubKeyBLS12381
+	var sigInf SignatureBLS12381
+	pubInf[0], sigInf[0] = 0xc0, 0xc0
+	assert.False(t, pubInf.VerifyBytes(blsVectorMsgs[2], sigInf))
+	assert.False(t, pubInf.VerifyPossession(sigInf))
+}
@@ -0,0 +1,76 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	secp256k1 "github.com/btcsuite/btcd/btcec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Derived keys must never change between releases. The ed25519 seeds
+// are those of Generate before deriveSeed existed.
+func TestDeriveVectors(t *testing.T) {
+	cases := []struct {
+		parent DeterministicDeriver
+		index  int
+		child  string // ed25519 seed or secp256k1 scalar
+	}{
+		{GenPrivKeyEd25519FromSecret([]byte("parent")), 0, "8b8047f3e3382ea153b43c84eb9cd9e2ae3e08d319db1105bc1f25650852efdd"},
+		{GenPrivKeyEd25519FromSecret([]byte("parent")), 1, "9c56027b83c864e15c2540f15626c00906f896f9c0733c6e5ed4df498ddd5e9d"},
+		{GenPrivKeyEd25519FromSecret([]byte("parent")), 1000, "a9d2fa0b910f6190a5f27b0d9d241fcb586ad41245d17e431ad771ae330fb6f7"},
+		{GenPrivKeyEd25519FromSecret([]byte("parent")), -1, "2032577701c4fb7f54af81137808cc323ffd25dc92f7518f7edc030fb6187bfb"},
+		{GenPrivKeySecp256k1FromSecret([]byte("parent")), 0, "0cac2628570c42b97dc5b39576f65e9f89e4e812967b1f79cea1c55beec264f6"},
+		{GenPrivKeySecp256k1FromSecret([]byte("parent")), 1, "961ed917b924c3f4c9b055fe498d944860f0d003eaa45f4424ea665903882228"},
+		{GenPrivKeySecp256k1FromSecret([]byte("parent")), 1000, "dbd222963875cdda37a63e2780a570e971d987a833a721326cf9a5580fc0433d"},
+		{GenPrivKeySecp256k1FromSecret([]byte("parent")), -1, "3ad7c72491952e61526da47aa4654c191f946861e8edc41de96a86bdb8c12a06"},
+	}
+	for _, tc := range cases {
+		child := tc.parent.Derive(tc.index)
+		switch child := child.(type) {
+		case PrivKeyEd25519:
+			assert.Equal(t, tc.child, hex.EncodeToString(child[:32]), "%d", tc.index)
+		case PrivKeySecp256k1:
+			assert.Equal(t, tc.child, hex.EncodeToString(child[:]), "%d", tc.index)
+		default:
+			t.Fatalf("Unexpected child type %T", child)
+		}
+		assert.True(t, child.Equals(tc.parent.Derive(tc.index)))
+		assert.False(t, child.Equals(tc.parent.Derive(tc.index+1)))
+
+		// children are usable keys
+		msg := CRandBytes(32)
+		assert.True(t, child.PubKey().VerifyBytes(msg, MustSign(child, msg)))
+	}
+}
+
+// The layout hashed by deriveSeed, spelled out: the prefix, version 1,
+// the type, the parent and index 1.
+func TestDeriveSeedLayout(t *testing.T) {
+	parent := GenPrivKeySecp256k1FromSecret([]byte("parent"))
+	layout, err := hex.DecodeString("74656e6465726d696e742f646572697665" + "01" +
+		"09" + "736563703235366b31" +
+		"20" + "e47125968b3b71049fbc4802d1e40a71ea1359decfabacf70b34588037d4ff0c" +
+		"0000000000000001")
+	require.Nil(t, err, "%+v", err)
+	require.Equal(t, "e47125968b3b71049fbc4802d1e40a71ea1359decfabacf70b34588037d4ff0c", hex.EncodeToString(parent[:]))
+	assert.Equal(t, Sha256(layout), deriveSeed("secp256k1", parent[:], 1))
+	child := parent.Generate(1)
+	assert.Equal(t, Sha256(layout), child[:])
+}
+
+func TestSecp256k1GenerateIsValid(t *testing.T) {
+	privKey := GenPrivKeySecp256k1()
+	for i := 0; i < 100; i++ {
+		child := privKey.Generate(i)
+		assert.True(t, isValidSecp256k1Scalar(child[:]), "%d", i)
+	}
+
+	order := secp256k1.S256().N
+	assert.False(t, isValidSecp256k1Scalar(make([]byte, 32)))
+	assert.False(t, isValidSecp256k1Scalar(intToBytes32(order)))
+	maxScalar := intToBytes32(new(big.Int).Sub(order, big.NewInt(1)))
+	require.True(t, isValidSecp256k1Scalar(maxScalar))
+}
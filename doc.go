@@ -12,7 +12,7 @@ which implements methods
 
     AssertIsPrivKeyInner()
     Bytes() []byte
-    Sign(msg []byte) Signature
+    Sign(msg []byte) (Signature, error)
     PubKey() PubKey
     Equals(PrivKey) bool
     Wrap() PrivKey
@@ -42,6 +42,10 @@ Sha256
 Ripemd160
     sum := crypto.Ripemd160([]byte("This is consensus"))
     fmt.Printf("%x\n", sum)
+
+Other hash functions are selected by HashFunc:
+    sum := crypto.HashKeccak256.Sum([]byte("This is Ethereum"))
+    h := crypto.HashBLAKE2b256.New() // a streaming io.Writer
 */
 package crypto
 
@@ -0,0 +1,84 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+
+	secp256k1 "github.com/btcsuite/btcd/btcec"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// SharedSecret computes a Diffie-Hellman shared secret between priv and
+// the public key of a peer. Both parties arrive at the same secret.
+//
+// Ed25519 keys are converted to Curve25519 (see ToCurve25519) and the
+// secret is the X25519 output. For secp256k1 the secret is the
+// x-coordinate of the shared point.
+//
+// The secret is not uniformly random: derive keys from it with
+// DeriveKey rather than using it directly.
+func SharedSecret(priv PrivKey, pub PubKey) ([]byte, error) {
+	switch priv := priv.(type) {
+	case PrivKeyEd25519:
+		pubEd, ok := pub.(PubKeyEd25519)
+		if !ok {
+			return nil, fmt.Errorf("Cannot agree on a secret between %T and %T", priv, pub)
+		}
+		return sharedSecretCurve25519(priv, pubEd)
+	case PrivKeySecp256k1:
+		pubSecp, ok := pub.(PubKeySecp256k1)
+		if !ok {
+			return nil, fmt.Errorf("Cannot agree on a secret between %T and %T", priv, pub)
+		}
+		return sharedSecretSecp256k1(priv, pubSecp)
+	default:
+		return nil, fmt.Errorf("Key agreement is not supported for %T", priv)
+	}
+}
+
+func sharedSecretCurve25519(priv PrivKeyEd25519, pub PubKeyEd25519) ([]byte, error) {
+	pubCurve := pub.ToCurve25519()
+	if pubCurve == nil {
+		return nil, errors.New("Invalid Ed25519 public key")
+	}
+	secret := new([32]byte)
+	curve25519.ScalarMult(secret, priv.ToCurve25519(), pubCurve)
+	// A low order point yields the all zero output, which would let the
+	// peer force a known secret.
+	if subtle.ConstantTimeCompare(secret[:], make([]byte, 32)) == 1 {
+		return nil, errors.New("Public key is a low order point")
+	}
+	return secret[:], nil
+}
+
+func sharedSecretSecp256k1(priv PrivKeySecp256k1, pub PubKeySecp256k1) ([]byte, error) {
+	// ParsePubKey checks that the point is on the curve.
+	pub__, err := secp256k1.ParsePubKey(pub[:], secp256k1.S256())
+	if err != nil {
+		return nil, err
+	}
+	priv__, _ := secp256k1.PrivKeyFromBytes(secp256k1.S256(), priv[:])
+	if priv__.D.Sign() == 0 || priv__.D.Cmp(secp256k1.S256().N) >= 0 {
+		return nil, errors.New("Invalid secp256k1 private key")
+	}
+	return secp256k1.GenerateSharedSecret(priv__, pub__), nil
+}
+
+// DeriveKey derives a key of the given length from a shared secret
+// with HKDF-SHA256 (RFC 5869). Use distinct info values to derive
+// independent keys from the same secret, e.g. one per direction.
+// salt may be nil.
+func DeriveKey(secret, salt, info []byte, length int) ([]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("Secret must not be empty")
+	}
+	key := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
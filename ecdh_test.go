@@ -0,0 +1,80 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedSecret(t *testing.T) {
+	cases := []struct {
+		a, b PrivKey
+	}{
+		{GenPrivKeyEd25519(), GenPrivKeyEd25519()},
+		{GenPrivKeySecp256k1(), GenPrivKeySecp256k1()},
+	}
+
+	for _, tc := range cases {
+		ab, err := SharedSecret(tc.a, tc.b.PubKey())
+		require.Nil(t, err, "%+v", err)
+		ba, err := SharedSecret(tc.b, tc.a.PubKey())
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, ab, ba)
+		assert.Len(t, ab, 32)
+
+		// a third party gets a different secret
+		other, err := SharedSecret(tc.a, tc.a.PubKey())
+		require.Nil(t, err, "%+v", err)
+		assert.NotEqual(t, ab, other)
+	}
+}
+
+func TestSharedSecretMismatchedKeys(t *testing.T) {
+	_, err := SharedSecret(GenPrivKeyEd25519(), GenPrivKeySecp256k1().PubKey())
+	assert.NotNil(t, err)
+	_, err = SharedSecret(GenPrivKeySecp256k1(), GenPrivKeyEd25519().PubKey())
+	assert.NotNil(t, err)
+	_, err = SharedSecret(GenPrivKeySchnorr(), GenPrivKeySchnorr().PubKey())
+	assert.NotNil(t, err)
+}
+
+func TestSharedSecretRejectsLowOrderPoints(t *testing.T) {
+	lowOrder := []string{
+		// identity
+		"0100000000000000000000000000000000000000000000000000000000000000",
+		// order 2
+		"ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+		// order 4
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		// order 8
+		"c7176a703d4dd84fba3c0b760d10670f2a2053fa2c39ccc64ec7fd7792ac037a",
+	}
+	priv := GenPrivKeyEd25519()
+	for _, h := range lowOrder {
+		bz, err := hex.DecodeString(h)
+		require.Nil(t, err)
+		var pub PubKeyEd25519
+		copy(pub[:], bz)
+		_, err = SharedSecret(priv, pub)
+		assert.NotNil(t, err, h)
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	// RFC 5869, test case 1
+	ikm, _ := hex.DecodeString("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b")
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	key, err := DeriveKey(ikm, salt, info, 42)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865", hex.EncodeToString(key))
+
+	other, err := DeriveKey(ikm, salt, []byte("other"), 42)
+	require.Nil(t, err, "%+v", err)
+	assert.NotEqual(t, key, other)
+
+	_, err = DeriveKey(nil, salt, info, 32)
+	assert.NotNil(t, err)
+}
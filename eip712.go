@@ -0,0 +1,389 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// This file implements hashing of typed structured data (EIP-712), as
+// signed by eth_signTypedData_v4.
+
+const eip712Domain = "EIP712Domain"
+
+// TypedDataField is a member of a struct type.
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedData is the input of eth_signTypedData_v4. Types must include
+// EIP712Domain, the type of Domain.
+//
+// Values of Domain and Message are as decoded from JSON by
+// ParseTypedData: objects for structs, arrays, strings, booleans and
+// numbers. Integers may also be decimal or 0x hex strings, addresses
+// and bytes are 0x hex strings. Go values of type int, int64, uint64,
+// *big.Int, []byte and EthAddress are accepted as well.
+type TypedData struct {
+	Types       map[string][]TypedDataField `json:"types"`
+	PrimaryType string                      `json:"primaryType"`
+	Domain      map[string]interface{}      `json:"domain"`
+	Message     map[string]interface{}      `json:"message"`
+}
+
+// ParseTypedData decodes the JSON of typed data. Numbers are kept
+// exact.
+func ParseTypedData(js []byte) (TypedData, error) {
+	var td TypedData
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	err := dec.Decode(&td)
+	return td, err
+}
+
+// Hash returns the digest to sign:
+// Keccak256("\x19\x01" || hashStruct(domain) || hashStruct(message)).
+func (td TypedData) Hash() ([]byte, error) {
+	if _, ok := td.Types[eip712Domain]; !ok {
+		return nil, errors.New("Typed data has no EIP712Domain type")
+	}
+	domainHash, err := td.HashStruct(eip712Domain, td.Domain)
+	if err != nil {
+		return nil, fmt.Errorf("Domain: %v", err)
+	}
+	h := HashKeccak256.New()
+	h.Write([]byte{0x19, 0x01})
+	h.Write(domainHash)
+	if td.PrimaryType != eip712Domain {
+		messageHash, err := td.HashStruct(td.PrimaryType, td.Message)
+		if err != nil {
+			return nil, fmt.Errorf("Message: %v", err)
+		}
+		h.Write(messageHash)
+	}
+	return h.Sum(nil), nil
+}
+
+// SignTypedData signs the hash of td like eth_signTypedData_v4.
+func (privKey PrivKeySecp256k1) SignTypedData(td TypedData) (SignatureEthereum, error) {
+	hash, err := td.Hash()
+	if err != nil {
+		return SignatureEthereum{}, err
+	}
+	return privKey.SignEthereumHash(hash)
+}
+
+// VerifyTypedData returns true iff sig is a signature of td by the key
+// with the given address.
+func VerifyTypedData(addr EthAddress, td TypedData, sig SignatureEthereum) bool {
+	hash, err := td.Hash()
+	return err == nil && VerifyEthereumHash(addr, hash, sig)
+}
+
+// HashStruct returns Keccak256(typeHash || encodeData(data)) of a value
+// of the named struct type.
+func (td TypedData) HashStruct(typ string, data map[string]interface{}) ([]byte, error) {
+	encoded, err := td.encodeData(typ, data, 0)
+	if err != nil {
+		return nil, err
+	}
+	return HashKeccak256.Sum(encoded), nil
+}
+
+// TypeHash returns the Keccak256 of the encoding of the named type.
+func (td TypedData) TypeHash(typ string) ([]byte, error) {
+	encoded, err := td.EncodeType(typ)
+	if err != nil {
+		return nil, err
+	}
+	return HashKeccak256.Sum([]byte(encoded)), nil
+}
+
+// EncodeType returns the encoding of the named struct type, e.g.
+// "Mail(Person from,Person to,string contents)Person(string name,address wallet)".
+// Referenced struct types follow in alphabetical order.
+func (td TypedData) EncodeType(typ string) (string, error) {
+	deps := map[string]bool{}
+	if err := td.dependencies(typ, deps); err != nil {
+		return "", err
+	}
+	delete(deps, typ)
+	sorted := make([]string, 0, len(deps))
+	for dep := range deps {
+		sorted = append(sorted, dep)
+	}
+	sort.Strings(sorted)
+
+	var buf bytes.Buffer
+	for _, name := range append([]string{typ}, sorted...) {
+		buf.WriteString(name)
+		buf.WriteByte('(')
+		for i, field := range td.Types[name] {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(field.Type)
+			buf.WriteByte(' ')
+			buf.WriteString(field.Name)
+		}
+		buf.WriteByte(')')
+	}
+	return buf.String(), nil
+}
+
+func (td TypedData) dependencies(typ string, deps map[string]bool) error {
+	if deps[typ] {
+		return nil
+	}
+	fields, ok := td.Types[typ]
+	if !ok {
+		return fmt.Errorf("Unknown type %q", typ)
+	}
+	deps[typ] = true
+	for _, field := range fields {
+		base := baseType(field.Type)
+		if _, ok := td.Types[base]; ok {
+			if err := td.dependencies(base, deps); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// maxTypedDataDepth bounds the nesting of structs and arrays.
+const maxTypedDataDepth = 32
+
+func (td TypedData) encodeData(typ string, data map[string]interface{}, depth int) ([]byte, error) {
+	if depth > maxTypedDataDepth {
+		return nil, errors.New("Typed data is nested too deeply")
+	}
+	typeHash, err := td.TypeHash(typ)
+	if err != nil {
+		return nil, err
+	}
+	fields := td.Types[typ]
+	buf := make([]byte, 0, 32*(1+len(fields)))
+	buf = append(buf, typeHash...)
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s.%s is missing", typ, field.Name)
+		}
+		encoded, err := td.encodeValue(field.Type, value, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", typ, field.Name, err)
+		}
+		buf = append(buf, encoded...)
+	}
+	return buf, nil
+}
+
+var arrayTypeRe = regexp.MustCompile(`^(.+)\[([0-9]*)\]$`)
+
+// baseType strips all array suffixes from typ.
+func baseType(typ string) string {
+	for {
+		m := arrayTypeRe.FindStringSubmatch(typ)
+		if m == nil {
+			return typ
+		}
+		typ = m[1]
+	}
+}
+
+// encodeValue returns the 32 byte encoding of value of type typ.
+func (td TypedData) encodeValue(typ string, value interface{}, depth int) ([]byte, error) {
+	if m := arrayTypeRe.FindStringSubmatch(typ); m != nil {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Expected an array, got %T", value)
+		}
+		if m[2] != "" {
+			n, err := strconv.Atoi(m[2])
+			if err != nil || n != len(items) {
+				return nil, fmt.Errorf("Expected %s items, got %d", m[2], len(items))
+			}
+		}
+		if depth > maxTypedDataDepth {
+			return nil, errors.New("Typed data is nested too deeply")
+		}
+		buf := make([]byte, 0, 32*len(items))
+		for i, item := range items {
+			encoded, err := td.encodeValue(m[1], item, depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("Item %d: %v", i, err)
+			}
+			buf = append(buf, encoded...)
+		}
+		return HashKeccak256.Sum(buf), nil
+	}
+	if _, ok := td.Types[typ]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Expected an object, got %T", value)
+		}
+		encoded, err := td.encodeData(typ, data, depth)
+		if err != nil {
+			return nil, err
+		}
+		return HashKeccak256.Sum(encoded), nil
+	}
+
+	switch {
+	case typ == "string":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("Expected a string, got %T", value)
+		}
+		return HashKeccak256.Sum([]byte(s)), nil
+	case typ == "bytes":
+		bz, err := typedBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return HashKeccak256.Sum(bz), nil
+	case typ == "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("Expected a bool, got %T", value)
+		}
+		word := make([]byte, 32)
+		if b {
+			word[31] = 1
+		}
+		return word, nil
+	case typ == "address":
+		var addr EthAddress
+		switch v := value.(type) {
+		case EthAddress:
+			addr = v
+		default:
+			bz, err := typedBytes(value)
+			if err != nil {
+				return nil, err
+			}
+			if len(bz) != len(addr) {
+				return nil, fmt.Errorf("Invalid address size %d", len(bz))
+			}
+			copy(addr[:], bz)
+		}
+		word := make([]byte, 32)
+		copy(word[12:], addr[:])
+		return word, nil
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := strconv.Atoi(typ[len("bytes"):])
+		if err != nil || size < 1 || size > 32 {
+			return nil, fmt.Errorf("Unknown type %q", typ)
+		}
+		bz, err := typedBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(bz) != size {
+			return nil, fmt.Errorf("Expected %d bytes, got %d", size, len(bz))
+		}
+		word := make([]byte, 32)
+		copy(word, bz)
+		return word, nil
+	case strings.HasPrefix(typ, "uint"), strings.HasPrefix(typ, "int"):
+		signed := strings.HasPrefix(typ, "int")
+		bits, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(typ, "u"), "int"))
+		if err != nil || bits < 8 || bits > 256 || bits%8 != 0 {
+			return nil, fmt.Errorf("Unknown type %q", typ)
+		}
+		n, err := typedInt(value)
+		if err != nil {
+			return nil, err
+		}
+		return encodeTypedInt(n, bits, signed)
+	default:
+		return nil, fmt.Errorf("Unknown type %q", typ)
+	}
+}
+
+// typedBytes decodes a 0x hex string or returns a []byte as is.
+func typedBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		if !strings.HasPrefix(v, "0x") {
+			return nil, errors.New("Bytes must be 0x prefixed hex")
+		}
+		return hex.DecodeString(v[2:])
+	default:
+		return nil, fmt.Errorf("Expected hex bytes, got %T", value)
+	}
+}
+
+func typedInt(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, nil
+	case int:
+		return big.NewInt(int64(v)), nil
+	case int64:
+		return big.NewInt(v), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+			return nil, fmt.Errorf("Number %v is not an exact integer", v)
+		}
+		return big.NewInt(int64(v)), nil
+	case json.Number:
+		return parseTypedInt(string(v))
+	case string:
+		return parseTypedInt(v)
+	default:
+		return nil, fmt.Errorf("Expected an integer, got %T", value)
+	}
+}
+
+func parseTypedInt(s string) (*big.Int, error) {
+	n, ok := new(big.Int), false
+	switch {
+	case strings.HasPrefix(s, "0x"):
+		n, ok = n.SetString(s[2:], 16)
+	case strings.HasPrefix(s, "-0x"):
+		n, ok = n.SetString(s[3:], 16)
+		if ok {
+			n.Neg(n)
+		}
+	default:
+		n, ok = n.SetString(s, 10)
+	}
+	if !ok {
+		return nil, fmt.Errorf("Invalid integer %q", s)
+	}
+	return n, nil
+}
+
+// encodeTypedInt returns the 32 byte two's complement of n, checking
+// that it fits in bits.
+func encodeTypedInt(n *big.Int, bits int, signed bool) ([]byte, error) {
+	min, max := new(big.Int), new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	if signed {
+		max.Rsh(max, 1)
+		min.Neg(max)
+	}
+	if n.Cmp(min) < 0 || n.Cmp(max) >= 0 {
+		return nil, fmt.Errorf("Integer %s out of range", n)
+	}
+	if n.Sign() >= 0 {
+		return intToBytes32(n), nil
+	}
+	// 2^256 + n
+	twos := new(big.Int).Lsh(big.NewInt(1), 256)
+	return intToBytes32(twos.Add(twos, n)), nil
+}
@@ -69,7 +69,7 @@ func TestKeyEncodings(t *testing.T) {
 
 		// Check (de/en)codings of Signatures.
 		var sig1, sig2, sig3 Signature
-		sig1 = tc.privKey.Sign([]byte("something"))
+		sig1 = MustSign(tc.privKey, []byte("something"))
 		checkAminoBinary(t, sig1, &sig2, -1) // Siganture size changes for Secp anyways.
 		assert.EqualValues(t, sig1, sig2)
 		checkAminoJSON(t, sig1, &sig3, false) // TODO also check Prefix bytes.
@@ -0,0 +1,176 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	secp256k1 "github.com/btcsuite/btcd/btcec"
+)
+
+// This file implements Ethereum addresses (with EIP-55 checksums) and
+// Ethereum style signatures for secp256k1 keys: personal_sign messages
+// (EIP-191, version 0x45) and digests of typed data (EIP-712, see
+// TypedData).
+
+// EthAddress is an Ethereum address: the last 20 bytes of the
+// Keccak-256 of the uncompressed public key.
+type EthAddress [20]byte
+
+// EthereumAddress returns the Ethereum address of pubKey.
+func (pubKey PubKeySecp256k1) EthereumAddress() (EthAddress, error) {
+	pub__, err := secp256k1.ParsePubKey(pubKey[:], secp256k1.S256())
+	if err != nil {
+		return EthAddress{}, err
+	}
+	return ethAddressOf(pub__), nil
+}
+
+func ethAddressOf(pub__ *secp256k1.PublicKey) EthAddress {
+	var addr EthAddress
+	// drop the 0x04 prefix of the uncompressed encoding
+	hash := HashKeccak256.Sum(pub__.SerializeUncompressed()[1:])
+	copy(addr[:], hash[12:])
+	return addr
+}
+
+// Hex returns the 0x prefixed, EIP-55 checksummed hex encoding of addr.
+func (addr EthAddress) Hex() string {
+	lower := hex.EncodeToString(addr[:])
+	hash := HashKeccak256.Sum([]byte(lower))
+	checksummed := []byte(lower)
+	for i, c := range checksummed {
+		// upper case letters whose nibble in the hash is >= 8
+		nibble := hash[i/2] >> 4
+		if i%2 == 1 {
+			nibble = hash[i/2] & 0x0f
+		}
+		if c >= 'a' && nibble >= 8 {
+			checksummed[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(checksummed)
+}
+
+func (addr EthAddress) String() string {
+	return addr.Hex()
+}
+
+// EthAddressFromHex parses a 0x prefixed hex address. All lower or all
+// upper case addresses are accepted as is, mixed case addresses must
+// have a valid EIP-55 checksum.
+func EthAddressFromHex(s string) (EthAddress, error) {
+	var addr EthAddress
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return addr, errors.New("Ethereum address must start with 0x")
+	}
+	digits := s[2:]
+	bz, err := hex.DecodeString(digits)
+	if err != nil {
+		return addr, fmt.Errorf("Invalid Ethereum address: %v", err)
+	}
+	if len(bz) != len(addr) {
+		return addr, fmt.Errorf("Invalid Ethereum address size %d, expected %d", len(bz), len(addr))
+	}
+	copy(addr[:], bz)
+	if digits != strings.ToLower(digits) && digits != strings.ToUpper(digits) &&
+		addr.Hex()[2:] != digits {
+		return addr, errors.New("Invalid Ethereum address checksum")
+	}
+	return addr, nil
+}
+
+//-------------------------------------
+
+// SignatureEthereum is an Ethereum signature R || S || V, with V 27 or
+// 28 as returned by eth_sign and personal_sign.
+type SignatureEthereum [65]byte
+
+func (sig SignatureEthereum) Hex() string {
+	return "0x" + hex.EncodeToString(sig[:])
+}
+
+func (sig SignatureEthereum) String() string {
+	return sig.Hex()
+}
+
+// EthereumMessageHash returns the digest of msg signed by
+// personal_sign (EIP-191):
+// Keccak256("\x19Ethereum Signed Message:\n" || len(msg) || msg).
+func EthereumMessageHash(msg []byte) []byte {
+	h := HashKeccak256.New()
+	h.Write([]byte("\x19Ethereum Signed Message:\n"))
+	h.Write([]byte(strconv.Itoa(len(msg))))
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+// SignEthereumMessage signs msg like personal_sign.
+func (privKey PrivKeySecp256k1) SignEthereumMessage(msg []byte) (SignatureEthereum, error) {
+	return privKey.SignEthereumHash(EthereumMessageHash(msg))
+}
+
+// SignEthereumHash signs a 32 byte digest, e.g. from EthereumMessageHash
+// or TypedData.Hash. Never sign a digest you didn't compute yourself:
+// it may be the hash of a transaction.
+func (privKey PrivKeySecp256k1) SignEthereumHash(hash []byte) (SignatureEthereum, error) {
+	var sig SignatureEthereum
+	if len(hash) != 32 {
+		return sig, fmt.Errorf("Invalid hash size %d, expected 32", len(hash))
+	}
+	priv__, _ := secp256k1.PrivKeyFromBytes(secp256k1.S256(), privKey[:])
+	// V || R || S, with V = 27 + recovery id for uncompressed keys
+	compact, err := secp256k1.SignCompact(secp256k1.S256(), priv__, hash, false)
+	if err != nil {
+		return sig, err
+	}
+	copy(sig[:64], compact[1:])
+	sig[64] = compact[0]
+	return sig, nil
+}
+
+// RecoverEthereumAddress returns the address of the key which signed
+// hash. V may be 0, 1, 27 or 28. High-S signatures are rejected, as
+// they are by Ethereum since EIP-2.
+// NOTE: Any well formed signature recovers to some address, so the
+// result must be checked, e.g. with VerifyEthereumHash.
+func RecoverEthereumAddress(hash []byte, sig SignatureEthereum) (EthAddress, error) {
+	if len(hash) != 32 {
+		return EthAddress{}, fmt.Errorf("Invalid hash size %d, expected 32", len(hash))
+	}
+	v := sig[64]
+	if v >= 27 {
+		v -= 27
+	}
+	if v > 1 {
+		return EthAddress{}, fmt.Errorf("Invalid signature V %d", sig[64])
+	}
+	if new(big.Int).SetBytes(sig[32:64]).Cmp(secp256k1HalfOrder) > 0 {
+		return EthAddress{}, errors.New("Signature is not low-S")
+	}
+	compact := make([]byte, 0, 65)
+	compact = append(compact, 27+v)
+	compact = append(compact, sig[:64]...)
+	pub__, _, err := secp256k1.RecoverCompact(secp256k1.S256(), compact, hash)
+	if err != nil {
+		return EthAddress{}, err
+	}
+	return ethAddressOf(pub__), nil
+}
+
+// VerifyEthereumHash returns true iff sig is a signature of hash by the
+// key with the given address.
+func VerifyEthereumHash(addr EthAddress, hash []byte, sig SignatureEthereum) bool {
+	recovered, err := RecoverEthereumAddress(hash, sig)
+	return err == nil && bytes.Equal(recovered[:], addr[:])
+}
+
+// VerifyEthereumMessage returns true iff sig is a personal_sign
+// signature of msg by the key with the given address.
+func VerifyEthereumMessage(addr EthAddress, msg []byte, sig SignatureEthereum) bool {
+	return VerifyEthereumHash(addr, EthereumMessageHash(msg), sig)
+}
@@ -0,0 +1,195 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the private key of "cow" in the EIP-712 example
+func cowPrivKey() PrivKeySecp256k1 {
+	var privKey PrivKeySecp256k1
+	copy(privKey[:], HashKeccak256.Sum([]byte("cow")))
+	return privKey
+}
+
+func TestEthereumAddress(t *testing.T) {
+	pubKey := cowPrivKey().PubKey().(PubKeySecp256k1)
+	addr, err := pubKey.EthereumAddress()
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826", addr.Hex())
+}
+
+func TestEthAddressChecksum(t *testing.T) {
+	// EIP-55 test vectors
+	for _, s := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+		"0x52908400098527886E0F7030069857D2E4169EE7",
+		"0xde709f2102306220921060314715629080e2fb77",
+	} {
+		addr, err := EthAddressFromHex(s)
+		require.Nil(t, err, "%s: %+v", s, err)
+		assert.Equal(t, s, addr.Hex())
+	}
+
+	for _, s := range []string{
+		"0x5aaeb6053F3E94C9b9A09f33669435E7Ef1BeAed", // bad checksum
+		"5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",   // no prefix
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeA",   // too short
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeg", // not hex
+	} {
+		_, err := EthAddressFromHex(s)
+		assert.NotNil(t, err, s)
+	}
+	// all lower and upper case addresses have no checksum
+	_, err := EthAddressFromHex("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	assert.Nil(t, err, "%+v", err)
+	_, err = EthAddressFromHex("0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED")
+	assert.Nil(t, err, "%+v", err)
+}
+
+func TestEthereumMessage(t *testing.T) {
+	assert.Equal(t,
+		"a1de988600a42c4b4ab089b619297c17d53cffae5d5120d82d8a92d0bb3b78f2",
+		hex.EncodeToString(EthereumMessageHash([]byte("Hello World"))))
+
+	privKey := GenPrivKeySecp256k1()
+	addr, err := privKey.PubKey().(PubKeySecp256k1).EthereumAddress()
+	require.Nil(t, err, "%+v", err)
+
+	msg := CRandBytes(128)
+	sig, err := privKey.SignEthereumMessage(msg)
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, sig[64] == 27 || sig[64] == 28)
+	assert.True(t, VerifyEthereumMessage(addr, msg, sig))
+	assert.False(t, VerifyEthereumMessage(addr, CRandBytes(128), sig))
+
+	// V as 0 or 1 is accepted too
+	legacy := sig
+	legacy[64] -= 27
+	assert.True(t, VerifyEthereumMessage(addr, msg, legacy))
+	legacy[64] = 2
+	assert.False(t, VerifyEthereumMessage(addr, msg, legacy))
+
+	other, err := GenPrivKeySecp256k1().PubKey().(PubKeySecp256k1).EthereumAddress()
+	require.Nil(t, err, "%+v", err)
+	assert.False(t, VerifyEthereumMessage(other, msg, sig))
+
+	_, err = privKey.SignEthereumHash([]byte("too short"))
+	assert.NotNil(t, err)
+}
+
+const mailTypedData = `{
+  "types": {
+    "EIP712Domain": [
+      {"name": "name", "type": "string"},
+      {"name": "version", "type": "string"},
+      {"name": "chainId", "type": "uint256"},
+      {"name": "verifyingContract", "type": "address"}
+    ],
+    "Person": [
+      {"name": "name", "type": "string"},
+      {"name": "wallet", "type": "address"}
+    ],
+    "Mail": [
+      {"name": "from", "type": "Person"},
+      {"name": "to", "type": "Person"},
+      {"name": "contents", "type": "string"}
+    ]
+  },
+  "primaryType": "Mail",
+  "domain": {
+    "name": "Ether Mail",
+    "version": "1",
+    "chainId": 1,
+    "verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+  },
+  "message": {
+    "from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+    "to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+    "contents": "Hello, Bob!"
+  }
+}`
+
+func TestTypedData(t *testing.T) {
+	// the example of EIP-712
+	td, err := ParseTypedData([]byte(mailTypedData))
+	require.Nil(t, err, "%+v", err)
+
+	encoded, err := td.EncodeType("Mail")
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, "Mail(Person from,Person to,string contents)Person(string name,address wallet)", encoded)
+
+	hash, err := td.Hash()
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, "be609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2", hex.EncodeToString(hash))
+
+	sig, err := cowPrivKey().SignTypedData(td)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, "0x"+
+		"4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d"+
+		"07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562"+
+		"1c", sig.Hex())
+
+	cow, err := EthAddressFromHex("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826")
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, VerifyTypedData(cow, td, sig))
+
+	td.Message["contents"] = "Hello, Eve!"
+	assert.False(t, VerifyTypedData(cow, td, sig))
+}
+
+func TestTypedDataValues(t *testing.T) {
+	td := TypedData{
+		Types: map[string][]TypedDataField{
+			"EIP712Domain": {{Name: "chainId", Type: "uint256"}},
+			"Transfer": {
+				{Name: "amount", Type: "int8"},
+				{Name: "ok", Type: "bool"},
+				{Name: "tag", Type: "bytes4"},
+				{Name: "data", Type: "bytes"},
+				{Name: "ids", Type: "uint64[2]"},
+			},
+		},
+		PrimaryType: "Transfer",
+		Domain:      map[string]interface{}{"chainId": "0x1"},
+		Message: map[string]interface{}{
+			"amount": -1,
+			"ok":     true,
+			"tag":    "0x01020304",
+			"data":   []byte{1, 2, 3},
+			"ids":    []interface{}{1.0, "2"},
+		},
+	}
+	_, err := td.Hash()
+	require.Nil(t, err, "%+v", err)
+
+	word, err := encodeTypedInt(big.NewInt(-1), 8, true)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", hex.EncodeToString(word))
+
+	cases := map[string]interface{}{
+		"amount": 128,                // out of range for int8
+		"ok":     "true",             // not a bool
+		"tag":    "0x010203",         // wrong size
+		"data":   "010203",           // not 0x prefixed
+		"ids":    []interface{}{1.0}, // wrong length
+	}
+	for field, value := range cases {
+		saved := td.Message[field]
+		td.Message[field] = value
+		_, err := td.Hash()
+		assert.NotNil(t, err, field)
+		td.Message[field] = saved
+	}
+
+	delete(td.Message, "ok")
+	_, err = td.Hash()
+	assert.NotNil(t, err)
+}
@@ -2,7 +2,14 @@ package crypto
 
 import (
 	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
 	"golang.org/x/crypto/ripemd160"
+	"golang.org/x/crypto/sha3"
 )
 
 func Sha256(bytes []byte) []byte {
@@ -16,3 +23,183 @@ func Ripemd160(bytes []byte) []byte {
 	hasher.Write(bytes)
 	return hasher.Sum(nil)
 }
+
+//-------------------------------------
+
+// HashFunc identifies a hash function. It is serialized as a single
+// byte, so ids must never be reused. As text (e.g. in JSON), it is
+// serialized by name.
+type HashFunc byte
+
+const (
+	HashSHA256         HashFunc = 0x01
+	HashSHA512         HashFunc = 0x02
+	HashSHA512_256     HashFunc = 0x03
+	HashSHA3_256       HashFunc = 0x04
+	HashSHA3_512       HashFunc = 0x05
+	HashKeccak256      HashFunc = 0x06
+	HashBLAKE2b256     HashFunc = 0x07
+	HashBLAKE2b512     HashFunc = 0x08
+	HashBLAKE2s256     HashFunc = 0x09
+	HashRIPEMD160      HashFunc = 0x0a
+	HashSHA256_160     HashFunc = 0x0b // SHA-256 truncated to 20 bytes
+	HashSHA224         HashFunc = 0x0c
+	HashSHA384         HashFunc = 0x0d
+	HashSHA3_224       HashFunc = 0x0e
+	HashSHA3_384       HashFunc = 0x0f
+	HashBLAKE2b256_160 HashFunc = 0x10 // BLAKE2b-256 truncated to 20 bytes
+)
+
+type hasher struct {
+	name    string
+	newHash func() hash.Hash
+}
+
+var (
+	hashers      = map[HashFunc]hasher{}
+	hashesByName = map[string]HashFunc{}
+)
+
+func init() {
+	RegisterHasher(HashSHA256, "sha256", sha256.New)
+	RegisterHasher(HashSHA512, "sha512", sha512.New)
+	RegisterHasher(HashSHA512_256, "sha512-256", sha512.New512_256)
+	RegisterHasher(HashSHA224, "sha224", sha256.New224)
+	RegisterHasher(HashSHA384, "sha384", sha512.New384)
+	RegisterHasher(HashSHA3_224, "sha3-224", sha3.New224)
+	RegisterHasher(HashSHA3_256, "sha3-256", sha3.New256)
+	RegisterHasher(HashSHA3_384, "sha3-384", sha3.New384)
+	RegisterHasher(HashSHA3_512, "sha3-512", sha3.New512)
+	RegisterHasher(HashKeccak256, "keccak256", sha3.NewLegacyKeccak256)
+	RegisterHasher(HashBLAKE2b256, "blake2b-256", newBLAKE2b(32))
+	RegisterHasher(HashBLAKE2b512, "blake2b-512", newBLAKE2b(64))
+	RegisterHasher(HashBLAKE2s256, "blake2s-256", newBLAKE2s256)
+	RegisterHasher(HashRIPEMD160, "ripemd160", ripemd160.New)
+	RegisterHasher(HashSHA256_160, "sha256-160", Truncated(sha256.New, 20))
+	RegisterHasher(HashBLAKE2b256_160, "blake2b-256-160", Truncated(newBLAKE2b(32), 20))
+}
+
+// RegisterHasher makes a hash function available under id and name.
+// It panics if either is already taken, so it should be called from
+// an init function.
+func RegisterHasher(id HashFunc, name string, newHash func() hash.Hash) {
+	if _, ok := hashers[id]; ok {
+		panic(fmt.Sprintf("HashFunc %d is already registered", id))
+	}
+	if _, ok := hashesByName[name]; ok {
+		panic(fmt.Sprintf("HashFunc %q is already registered", name))
+	}
+	hashers[id] = hasher{name, newHash}
+	hashesByName[name] = id
+}
+
+// HashFuncByName returns the hash function registered under name.
+func HashFuncByName(name string) (HashFunc, error) {
+	h, ok := hashesByName[name]
+	if !ok {
+		return 0, fmt.Errorf("Unknown hash function %q", name)
+	}
+	return h, nil
+}
+
+// Available returns true iff h is registered.
+func (h HashFunc) Available() bool {
+	_, ok := hashers[h]
+	return ok
+}
+
+// New returns a new hash.Hash, which is an io.Writer, computing h.
+// It panics if h is not registered.
+func (h HashFunc) New() hash.Hash {
+	hr, ok := hashers[h]
+	if !ok {
+		panic(fmt.Sprintf("HashFunc %d is not registered", h))
+	}
+	return hr.newHash()
+}
+
+// Size returns the digest size of h in bytes.
+func (h HashFunc) Size() int {
+	return h.New().Size()
+}
+
+// Sum returns the digest of bz.
+func (h HashFunc) Sum(bz []byte) []byte {
+	hr := h.New()
+	hr.Write(bz) // does not error
+	return hr.Sum(nil)
+}
+
+func (h HashFunc) String() string {
+	if hr, ok := hashers[h]; ok {
+		return hr.name
+	}
+	return fmt.Sprintf("HashFunc(%d)", byte(h))
+}
+
+func (h HashFunc) MarshalText() ([]byte, error) {
+	if !h.Available() {
+		return nil, fmt.Errorf("HashFunc %d is not registered", h)
+	}
+	return []byte(h.String()), nil
+}
+
+func (h *HashFunc) UnmarshalText(text []byte) error {
+	id, err := HashFuncByName(string(text))
+	if err != nil {
+		return err
+	}
+	*h = id
+	return nil
+}
+
+//-------------------------------------
+
+// Truncated returns a constructor of hashes whose digest is the first
+// size bytes of the digest of newHash.
+func Truncated(newHash func() hash.Hash, size int) func() hash.Hash {
+	if size <= 0 || size > newHash().Size() {
+		panic(fmt.Sprintf("Invalid truncated hash size %d", size))
+	}
+	return func() hash.Hash {
+		return truncatedHash{newHash(), size}
+	}
+}
+
+type truncatedHash struct {
+	hash.Hash
+	size int
+}
+
+func (h truncatedHash) Size() int { return h.size }
+
+func (h truncatedHash) Sum(b []byte) []byte {
+	sum := h.Hash.Sum(nil)
+	return append(b, sum[:h.size]...)
+}
+
+func newBLAKE2b(size int) func() hash.Hash {
+	return func() hash.Hash {
+		h, err := blake2b.New(size, nil)
+		if err != nil {
+			panic(err) // only for an invalid size or key
+		}
+		return h
+	}
+}
+
+func newBLAKE2s256() hash.Hash {
+	h, err := blake2s.New256(nil)
+	if err != nil {
+		panic(err) // only for an invalid key
+	}
+	return h
+}
+
+//-------------------------------------
+
+// AddressWithHash returns an address of pubKey derived with h instead
+// of the key type's own scheme: the digest of pubKey.Bytes().
+func AddressWithHash(pubKey PubKey, h HashFunc) Address {
+	return Address(h.Sum(pubKey.Bytes()))
+}
@@ -0,0 +1,97 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashFuncs(t *testing.T) {
+	cases := []struct {
+		h     HashFunc
+		input string
+		sum   string
+	}{
+		{HashSHA256, "abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{HashSHA512_256, "abc", "53048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23"},
+		{HashSHA3_256, "abc", "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"},
+		{HashKeccak256, "", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{HashBLAKE2b512, "abc", "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+		{HashBLAKE2s256, "abc", "508c5e8c327c14e2e1a72ba34eeb452f37458b209ed63a294d999b4c86675982"},
+		{HashRIPEMD160, "abc", "8eb208f7e05d987a9b044a8e98c6b087f15a0bfc"},
+		{HashSHA256_160, "abc", "ba7816bf8f01cfea414140de5dae2223b00361a3"},
+	}
+	for _, tc := range cases {
+		sum := tc.h.Sum([]byte(tc.input))
+		assert.Equal(t, tc.sum, hex.EncodeToString(sum), tc.h.String())
+		assert.Equal(t, len(sum), tc.h.Size(), tc.h.String())
+
+		// streaming
+		hr := tc.h.New()
+		for _, c := range []byte(tc.input) {
+			hr.Write([]byte{c})
+		}
+		assert.Equal(t, sum, hr.Sum(nil), tc.h.String())
+	}
+	assert.Equal(t, Sha256([]byte("abc")), HashSHA256.Sum([]byte("abc")))
+	assert.Equal(t, Ripemd160([]byte("abc")), HashRIPEMD160.Sum([]byte("abc")))
+}
+
+func TestHashFuncRegistry(t *testing.T) {
+	for id := range hashers {
+		h, err := HashFuncByName(id.String())
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, id, h)
+	}
+	_, err := HashFuncByName("md5")
+	assert.NotNil(t, err)
+
+	unknown := HashFunc(0xff)
+	assert.False(t, unknown.Available())
+	assert.Panics(t, func() { unknown.New() })
+	assert.Panics(t, func() { RegisterHasher(HashSHA256, "sha256-again", HashSHA256.New) })
+	assert.Panics(t, func() { RegisterHasher(0xfe, "sha256", HashSHA256.New) })
+
+	// by name in JSON
+	js, err := json.Marshal(struct{ Hash HashFunc }{HashKeccak256})
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, `{"Hash":"keccak256"}`, string(js))
+	var v struct{ Hash HashFunc }
+	require.Nil(t, json.Unmarshal(js, &v))
+	assert.Equal(t, HashKeccak256, v.Hash)
+	assert.NotNil(t, json.Unmarshal([]byte(`{"Hash":"md5"}`), &v))
+	_, err = json.Marshal(struct{ Hash HashFunc }{unknown})
+	assert.NotNil(t, err)
+}
+
+func TestAddressWithHash(t *testing.T) {
+	pubKey := GenPrivKeyEd25519().PubKey()
+	assert.Equal(t, pubKey.Address(), AddressWithHash(pubKey, HashRIPEMD160))
+	assert.Len(t, AddressWithHash(pubKey, HashSHA256_160), 20)
+	assert.Len(t, AddressWithHash(pubKey, HashBLAKE2b256), 32)
+}
+
+func TestSignWithHash(t *testing.T) {
+	privKey := GenPrivKeySecp256k1()
+	pubKey := privKey.PubKey().(PubKeySecp256k1)
+	msg := CRandBytes(128)
+
+	sig, err := privKey.SignWithHash(msg, HashKeccak256)
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, pubKey.VerifyBytesWithHash(msg, sig, HashKeccak256))
+	assert.False(t, pubKey.VerifyBytesWithHash(msg, sig, HashSHA3_256))
+	assert.False(t, pubKey.VerifyBytes(msg, sig))
+
+	// the default prehash is SHA-256
+	sig, err = privKey.SignWithHash(msg, HashSHA256)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, MustSign(privKey, msg), sig)
+	assert.True(t, pubKey.VerifyBytes(msg, sig))
+
+	_, err = privKey.SignWithHash(msg, HashFunc(0xff))
+	assert.NotNil(t, err)
+	assert.False(t, pubKey.VerifyBytesWithHash(msg, sig, HashFunc(0xff)))
+}
@@ -0,0 +1,348 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	secp256k1 "github.com/btcsuite/btcd/btcec"
+)
+
+// This file implements JSON Web Keys (RFC 7517) for Ed25519 (RFC 8037)
+// and secp256k1 (RFC 8812) keys, and JSON Web Signatures (RFC 7515) in
+// the compact serialization with the EdDSA and ES256K algorithms.
+// Other key types have no registered JWK representation.
+//
+// The kid of a key is the hex encoding of its Address.
+
+const (
+	jwkKtyOKP = "OKP"
+	jwkKtyEC  = "EC"
+
+	jwkCrvEd25519   = "Ed25519"
+	jwkCrvSecp256k1 = "secp256k1"
+
+	jwsAlgEdDSA  = "EdDSA"
+	jwsAlgES256K = "ES256K"
+)
+
+var b64 = base64.RawURLEncoding
+
+// JWK is a JSON Web Key. D is only set for private keys.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+	D   string `json:"d,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+}
+
+// JWKSet is a JWK Set, e.g. for publishing the keys of a validator set.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWKSet returns the set of the public JWKs of pubKeys.
+func NewJWKSet(pubKeys []PubKey) (JWKSet, error) {
+	set := JWKSet{Keys: make([]JWK, 0, len(pubKeys))}
+	for _, pub := range pubKeys {
+		jwk, err := PubKeyToJWK(pub)
+		if err != nil {
+			return set, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// Key returns the JWK with the given kid.
+func (set JWKSet) Key(kid string) (JWK, bool) {
+	for _, jwk := range set.Keys {
+		if jwk.Kid == kid {
+			return jwk, true
+		}
+	}
+	return JWK{}, false
+}
+
+// PubKeys returns the public keys of the set, in order.
+func (set JWKSet) PubKeys() ([]PubKey, error) {
+	pubKeys := make([]PubKey, 0, len(set.Keys))
+	for i, jwk := range set.Keys {
+		pub, err := PubKeyFromJWK(jwk)
+		if err != nil {
+			return nil, fmt.Errorf("Key %d: %v", i, err)
+		}
+		pubKeys = append(pubKeys, pub)
+	}
+	return pubKeys, nil
+}
+
+//-------------------------------------
+
+// PubKeyToJWK returns the public JWK of pub.
+func PubKeyToJWK(pub PubKey) (JWK, error) {
+	switch pub := pub.(type) {
+	case PubKeyEd25519:
+		return JWK{
+			Kty: jwkKtyOKP,
+			Crv: jwkCrvEd25519,
+			X:   b64.EncodeToString(pub[:]),
+			Kid: pub.Address().String(),
+			Alg: jwsAlgEdDSA,
+		}, nil
+	case PubKeySecp256k1:
+		pub__, err := secp256k1.ParsePubKey(pub[:], secp256k1.S256())
+		if err != nil {
+			return JWK{}, err
+		}
+		return JWK{
+			Kty: jwkKtyEC,
+			Crv: jwkCrvSecp256k1,
+			X:   b64.EncodeToString(intToBytes32(pub__.X)),
+			Y:   b64.EncodeToString(intToBytes32(pub__.Y)),
+			Kid: pub.Address().String(),
+			Alg: jwsAlgES256K,
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("JWK is not supported for %T", pub)
+	}
+}
+
+// PrivKeyToJWK returns the private JWK of priv.
+func PrivKeyToJWK(priv PrivKey) (JWK, error) {
+	jwk, err := PubKeyToJWK(priv.PubKey())
+	if err != nil {
+		return jwk, err
+	}
+	switch priv := priv.(type) {
+	case PrivKeyEd25519:
+		jwk.D = b64.EncodeToString(priv[:ed25519SeedSize])
+	case PrivKeySecp256k1:
+		jwk.D = b64.EncodeToString(priv[:])
+	default:
+		return JWK{}, fmt.Errorf("JWK is not supported for %T", priv)
+	}
+	return jwk, nil
+}
+
+// PubKeyFromJWK returns the public key of a public or private JWK.
+// If the JWK has a kid, it must match the key's address.
+func PubKeyFromJWK(jwk JWK) (PubKey, error) {
+	var pub PubKey
+	switch {
+	case jwk.Kty == jwkKtyOKP && jwk.Crv == jwkCrvEd25519:
+		x, err := decodeJWKField(jwk.X, "x", len(PubKeyEd25519{}))
+		if err != nil {
+			return nil, err
+		}
+		var pubEd PubKeyEd25519
+		copy(pubEd[:], x)
+		pub = pubEd
+	case jwk.Kty == jwkKtyEC && jwk.Crv == jwkCrvSecp256k1:
+		x, err := decodeJWKField(jwk.X, "x", 32)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKField(jwk.Y, "y", 32)
+		if err != nil {
+			return nil, err
+		}
+		point := make([]byte, 0, 65)
+		point = append(point, 0x04)
+		point = append(point, x...)
+		point = append(point, y...)
+		// ParsePubKey checks that the point is on the curve.
+		pubSecp, err := parseSecp256k1Point(point)
+		if err != nil {
+			return nil, err
+		}
+		pub = pubSecp
+	default:
+		return nil, fmt.Errorf("Unsupported JWK key type %q with curve %q", jwk.Kty, jwk.Crv)
+	}
+	if jwk.Kid != "" && jwk.Kid != pub.Address().String() {
+		return nil, errors.New("JWK kid does not match the key's address")
+	}
+	return pub, nil
+}
+
+// PrivKeyFromJWK returns the private key of a private JWK.
+// The public part of the JWK must match the private key.
+func PrivKeyFromJWK(jwk JWK) (PrivKey, error) {
+	pub, err := PubKeyFromJWK(jwk)
+	if err != nil {
+		return nil, err
+	}
+	if jwk.D == "" {
+		return nil, errors.New("JWK is not a private key")
+	}
+	var priv PrivKey
+	switch pub.(type) {
+	case PubKeyEd25519:
+		d, err := decodeJWKField(jwk.D, "d", ed25519SeedSize)
+		if err != nil {
+			return nil, err
+		}
+		priv = privKeyEd25519FromSeed(d)
+	case PubKeySecp256k1:
+		d, err := decodeJWKField(jwk.D, "d", 32)
+		if err != nil {
+			return nil, err
+		}
+		if n := new(big.Int).SetBytes(d); n.Sign() == 0 || n.Cmp(secp256k1.S256().N) >= 0 {
+			return nil, errors.New("Invalid secp256k1 private key")
+		}
+		var privSecp PrivKeySecp256k1
+		copy(privSecp[:], d)
+		priv = privSecp
+	}
+	if !priv.PubKey().Equals(pub) {
+		return nil, errors.New("JWK public key does not match the private key")
+	}
+	return priv, nil
+}
+
+func decodeJWKField(s, name string, size int) ([]byte, error) {
+	bz, err := b64.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid JWK %q: %v", name, err)
+	}
+	if len(bz) != size {
+		return nil, fmt.Errorf("Invalid JWK %q size %d, expected %d", name, len(bz), size)
+	}
+	return bz, nil
+}
+
+//-------------------------------------
+// JWS
+
+type jwsHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// SignJWS signs payload with signer and returns a JWS in the compact
+// serialization. The header names the key by its kid.
+func SignJWS(signer Signer, payload []byte) (string, error) {
+	pub := signer.PubKey()
+	alg, err := jwsAlgorithm(pub)
+	if err != nil {
+		return "", err
+	}
+	header, err := json.Marshal(jwsHeader{Alg: alg, Kid: pub.Address().String()})
+	if err != nil {
+		return "", err
+	}
+	signingInput := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+
+	var sigBytes []byte
+	switch signer := signer.(type) {
+	case PrivKeySecp256k1:
+		// ES256K signatures are R || S over the SHA-256 of the input.
+		sig, err := signer.SignFixed([]byte(signingInput))
+		if err != nil {
+			return "", err
+		}
+		sigBytes = sig[:]
+	default:
+		sig, err := signer.Sign([]byte(signingInput))
+		if err != nil {
+			return "", err
+		}
+		switch sig := sig.(type) {
+		case SignatureEd25519:
+			sigBytes = sig[:]
+		case SignatureSecp256k1:
+			fixed, err := sig.ToFixed()
+			if err != nil {
+				return "", err
+			}
+			sigBytes = fixed[:]
+		default:
+			return "", fmt.Errorf("Unexpected signature type %T for %s", sig, alg)
+		}
+	}
+	return signingInput + "." + b64.EncodeToString(sigBytes), nil
+}
+
+// VerifyJWS verifies a compact JWS against pub and returns its payload.
+// The alg of the header must be the one of pub, and a kid, if
+// present, must match pub's address.
+func VerifyJWS(pub PubKey, jws string) ([]byte, error) {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("JWS must have three parts")
+	}
+	headerBytes, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid JWS header: %v", err)
+	}
+	var header jwsHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, fmt.Errorf("Invalid JWS header: %v", err)
+	}
+	alg, err := jwsAlgorithm(pub)
+	if err != nil {
+		return nil, err
+	}
+	if header.Alg != alg {
+		return nil, fmt.Errorf("Unexpected JWS alg %q, expected %q", header.Alg, alg)
+	}
+	if header.Kid != "" && header.Kid != pub.Address().String() {
+		return nil, errors.New("JWS kid does not match the key")
+	}
+	payload, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid JWS payload: %v", err)
+	}
+	sigBytes, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid JWS signature: %v", err)
+	}
+
+	var sig Signature
+	switch pub.(type) {
+	case PubKeyEd25519:
+		var sigEd SignatureEd25519
+		if len(sigBytes) != len(sigEd) {
+			return nil, errors.New("Invalid JWS signature size")
+		}
+		copy(sigEd[:], sigBytes)
+		sig = sigEd
+	case PubKeySecp256k1:
+		if len(sigBytes) != len(SignatureSecp256k1Fixed{}) {
+			return nil, errors.New("Invalid JWS signature size")
+		}
+		r := new(big.Int).SetBytes(sigBytes[:32])
+		s := new(big.Int).SetBytes(sigBytes[32:])
+		if s.Cmp(secp256k1.S256().N) >= 0 {
+			return nil, errors.New("Invalid JWS signature")
+		}
+		// Other implementations may produce high-S signatures,
+		// which are just as valid for JWS.
+		sig = newSignatureSecp256k1Fixed(r, s)
+	}
+	signingInput := parts[0] + "." + parts[1]
+	if !pub.VerifyBytes([]byte(signingInput), sig) {
+		return nil, errors.New("JWS signature verification failed")
+	}
+	return payload, nil
+}
+
+func jwsAlgorithm(pub PubKey) (string, error) {
+	switch pub.(type) {
+	case PubKeyEd25519:
+		return jwsAlgEdDSA, nil
+	case PubKeySecp256k1:
+		return jwsAlgES256K, nil
+	default:
+		return "", fmt.Errorf("JWS is not supported for %T", pub)
+	}
+}
@@ -0,0 +1,138 @@
+package crypto
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	secp256k1 "github.com/btcsuite/btcd/btcec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 8037, appendix A
+const (
+	rfc8037PrivJWK = `{"kty":"OKP","crv":"Ed25519",
+"d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A",
+"x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`
+	rfc8037JWS = "eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc.hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg"
+)
+
+func TestJWKRFC8037(t *testing.T) {
+	var jwk JWK
+	require.Nil(t, json.Unmarshal([]byte(rfc8037PrivJWK), &jwk))
+	priv, err := PrivKeyFromJWK(jwk)
+	require.Nil(t, err, "%+v", err)
+
+	payload, err := VerifyJWS(priv.PubKey(), rfc8037JWS)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, "Example of Ed25519 signing", string(payload))
+
+	// Ed25519 is deterministic, so we produce the same signature
+	// for the same header.
+	sig, err := priv.Sign([]byte(rfc8037JWS[:strings.LastIndex(rfc8037JWS, ".")]))
+	require.Nil(t, err, "%+v", err)
+	sigEd := sig.(SignatureEd25519)
+	assert.True(t, strings.HasSuffix(rfc8037JWS, b64.EncodeToString(sigEd[:])))
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+	privKeys := []PrivKey{GenPrivKeyEd25519(), GenPrivKeySecp256k1()}
+	for _, priv := range privKeys {
+		jwk, err := PrivKeyToJWK(priv)
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, priv.PubKey().Address().String(), jwk.Kid)
+
+		bz, err := json.Marshal(jwk)
+		require.Nil(t, err, "%+v", err)
+		var jwk2 JWK
+		require.Nil(t, json.Unmarshal(bz, &jwk2))
+		priv2, err := PrivKeyFromJWK(jwk2)
+		require.Nil(t, err, "%+v", err)
+		assert.True(t, priv.Equals(priv2))
+
+		pubJWK, err := PubKeyToJWK(priv.PubKey())
+		require.Nil(t, err, "%+v", err)
+		assert.Empty(t, pubJWK.D)
+		pub, err := PubKeyFromJWK(pubJWK)
+		require.Nil(t, err, "%+v", err)
+		assert.True(t, priv.PubKey().Equals(pub))
+		_, err = PrivKeyFromJWK(pubJWK)
+		assert.NotNil(t, err)
+
+		// the kid must match
+		pubJWK.Kid = "ABCD"
+		_, err = PubKeyFromJWK(pubJWK)
+		assert.NotNil(t, err)
+	}
+
+	_, err := PubKeyToJWK(GenPrivKeySchnorr().PubKey())
+	assert.NotNil(t, err)
+}
+
+func TestJWKSet(t *testing.T) {
+	pubKeys := []PubKey{GenPrivKeyEd25519().PubKey(), GenPrivKeySecp256k1().PubKey()}
+	set, err := NewJWKSet(pubKeys)
+	require.Nil(t, err, "%+v", err)
+
+	bz, err := json.Marshal(set)
+	require.Nil(t, err, "%+v", err)
+	var set2 JWKSet
+	require.Nil(t, json.Unmarshal(bz, &set2))
+	pubKeys2, err := set2.PubKeys()
+	require.Nil(t, err, "%+v", err)
+	require.Len(t, pubKeys2, len(pubKeys))
+	for i := range pubKeys {
+		assert.True(t, pubKeys[i].Equals(pubKeys2[i]))
+	}
+
+	jwk, ok := set2.Key(pubKeys[1].Address().String())
+	require.True(t, ok)
+	assert.Equal(t, "ES256K", jwk.Alg)
+	_, ok = set2.Key("ABCD")
+	assert.False(t, ok)
+}
+
+func TestJWS(t *testing.T) {
+	privKeys := []PrivKey{GenPrivKeyEd25519(), GenPrivKeySecp256k1()}
+	payload := []byte(`{"height":42}`)
+	for _, priv := range privKeys {
+		jws, err := SignJWS(priv, payload)
+		require.Nil(t, err, "%+v", err)
+		payload2, err := VerifyJWS(priv.PubKey(), jws)
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, payload, payload2)
+
+		// tampered payload
+		parts := strings.Split(jws, ".")
+		tampered := parts[0] + "." + b64.EncodeToString([]byte(`{"height":43}`)) + "." + parts[2]
+		_, err = VerifyJWS(priv.PubKey(), tampered)
+		assert.NotNil(t, err)
+
+		// wrong key
+		_, err = VerifyJWS(GenPrivKeyEd25519().PubKey(), jws)
+		assert.NotNil(t, err)
+		_, err = VerifyJWS(GenPrivKeySecp256k1().PubKey(), jws)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestJWSES256KHighS(t *testing.T) {
+	priv := GenPrivKeySecp256k1()
+	jws, err := SignJWS(priv, []byte("payload"))
+	require.Nil(t, err, "%+v", err)
+
+	// flip S to the upper half of the order
+	parts := strings.Split(jws, ".")
+	sigBytes, err := b64.DecodeString(parts[2])
+	require.Nil(t, err, "%+v", err)
+	s := new(big.Int).SetBytes(sigBytes[32:])
+	s.Sub(secp256k1.S256().N, s)
+	copy(sigBytes[32:], intToBytes32(s))
+	highS := parts[0] + "." + parts[1] + "." + b64.EncodeToString(sigBytes)
+
+	payload, err := VerifyJWS(priv.PubKey(), highS)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, "payload", string(payload))
+}
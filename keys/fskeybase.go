@@ -0,0 +1,579 @@
+package keys
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	crypto "github.com/tendermint/go-crypto"
+	"github.com/tendermint/go-crypto/keys/words"
+)
+
+// fsKeybase stores each key in its own file in a directory, in the
+// Web3 Secret Storage format, so that Ethereum tools like geth can use
+// its secp256k1 keys, and keys can be backed up by copying files.
+//
+// A key named foo is in the file foo.json. The file also holds the
+// public key, so that keys can be listed without their passphrase.
+// Files without it, e.g. from geth, are listed with a nil PubKey, which
+// is added to the file the first time the key is unlocked. geth names
+// its files UTC--<date>--<address>, without extension: the key is named
+// like the file then. The PrivKeyArmor of an Info is the content of its
+// key file, even for offline keys.
+//
+// Files are written atomically. The directory and files must be
+// accessible only by their owner.
+type fsKeybase struct {
+	dir    string
+	codec  words.Codec
+	policy KDFPolicy
+}
+
+const (
+	fsKeyFileExt     = ".json"
+	fsMaxKeyFileSize = 1 << 20
+)
+
+// KeyFileErrors is returned by List of a file keybase, together with
+// the keys it could read, if some key files can't be read. It maps the
+// file names to their errors.
+type KeyFileErrors map[string]error
+
+func (errs KeyFileErrors) Error() string {
+	files := make([]string, 0, len(errs))
+	for file := range errs {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	msgs := make([]string, len(files))
+	for i, file := range files {
+		msgs[i] = fmt.Sprintf("%s: %v", file, errs[file])
+	}
+	return "Skipped key files: " + strings.Join(msgs, "; ")
+}
+
+// Web3KDFPolicy encrypts keys with scrypt with the parameters of geth,
+// N = 2^18, r = 8, p = 1, and accepts scrypt keys which meet the
+// DefaultKDFPolicy. pbkdf2 keys are always upgraded.
+var Web3KDFPolicy = KDFPolicy{
+	Current:   ScryptKDF{N: 1 << 18, R: 8, P: 1},
+	MinScrypt: DefaultKDFPolicy.MinScrypt,
+}
+
+// NewFS returns a keybase in dir with the Web3KDFPolicy. dir is created
+// if it doesn't exist.
+func NewFS(dir string, codec words.Codec) (fsKeybase, error) {
+	return NewFSWithKDFPolicy(dir, codec, Web3KDFPolicy)
+}
+
+// NewFSWithKDFPolicy returns a keybase in dir which encrypts keys with
+// the policy's current KDF, which must be scrypt or pbkdf2, and
+// upgrades keys below the policy.
+func NewFSWithKDFPolicy(dir string, codec words.Codec, policy KDFPolicy) (fsKeybase, error) {
+	switch policy.Current.(type) {
+	case ScryptKDF, PBKDF2KDF:
+	default:
+		return fsKeybase{}, errors.Errorf("Web3 Secret Storage doesn't support the KDF %T", policy.Current)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fsKeybase{}, err
+	}
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return fsKeybase{}, err
+	}
+	if !fi.IsDir() {
+		return fsKeybase{}, errors.Errorf("%s is not a directory", dir)
+	}
+	if err := checkOwnerOnly(dir, fi); err != nil {
+		return fsKeybase{}, err
+	}
+	return fsKeybase{
+		dir:    dir,
+		codec:  codec,
+		policy: policy,
+	}, nil
+}
+
+var _ Keybase = fsKeybase{}
+
+// Create generates a new key and writes it to a new file, encrypted
+// using the passphrase.  It returns the generated seedphrase
+// (mnemonic) and the key Info.  It returns an error if it fails to
+// generate a key for the given algo type, or if another key is
+// already stored under the same name.
+func (kb fsKeybase) Create(name, passphrase string, algo CryptoAlgo) (Info, string, error) {
+	if err := kb.checkNew(name); err != nil {
+		return Info{}, "", err
+	}
+	priv, seed, err := newSeedKey(kb.codec, algo)
+	if err != nil {
+		return Info{}, "", err
+	}
+	info, err := kb.writeKey(priv, name, passphrase)
+	if err != nil {
+		return Info{}, "", err
+	}
+	return info, seed, nil
+}
+
+// Recover converts a seedphrase to a private key and writes it to a new
+// file, encrypted with the given passphrase.
+func (kb fsKeybase) Recover(name, passphrase, seedphrase string) (Info, error) {
+	if err := kb.checkNew(name); err != nil {
+		return Info{}, err
+	}
+	priv, err := recoverSeedKey(kb.codec, seedphrase)
+	if err != nil {
+		return Info{}, err
+	}
+	return kb.writeKey(priv, name, passphrase)
+}
+
+// CreateOffline writes a watch-only key with the public key pub to a
+// new file. It returns an error if pub is nil.
+func (kb fsKeybase) CreateOffline(name string, pub crypto.PubKey) (Info, error) {
+	if err := kb.checkNew(name); err != nil {
+		return Info{}, err
+	}
+	info := newOfflineInfo(name, pub)
+	if err := info.validate(); err != nil {
+		return Info{}, err
+	}
+	bz, err := offlineWeb3Key(pub)
+	if err != nil {
+		return Info{}, err
+	}
+	path, _ := kb.path(name)
+	if err := writeFileAtomic(path, bz); err != nil {
+		return Info{}, err
+	}
+	info.PrivKeyArmor = string(bz)
+	return info, nil
+}
+
+// CreateLedger returns an UnsupportedKindError: Web3 Secret Storage key
+// files only hold local and offline keys. Use a keybase from New for
+// ledger keys.
+func (kb fsKeybase) CreateLedger(name string, key LedgerKey) (Info, error) {
+	return Info{}, UnsupportedKindError{Kind: KeyLedger}
+}
+
+// CreateRemote returns an UnsupportedKindError, like CreateLedger.
+func (kb fsKeybase) CreateRemote(name string, key RemoteKey) (Info, error) {
+	return Info{}, UnsupportedKindError{Kind: KeyRemote}
+}
+
+// List returns the keys in alphabetical order. Key files which can't be
+// read are skipped, and returned in a KeyFileErrors with the other
+// keys. Files without the .json extension which aren't key files are
+// ignored.
+func (kb fsKeybase) List() ([]Info, error) {
+	fis, err := ioutil.ReadDir(kb.dir)
+	if err != nil {
+		return nil, err
+	}
+	var res []Info
+	errs := KeyFileErrors{}
+	for _, fi := range fis {
+		name := strings.TrimSuffix(fi.Name(), fsKeyFileExt)
+		if fi.IsDir() || checkKeyName(name) != nil {
+			continue
+		}
+		// foo.json is the key foo, even if there is a file foo
+		if path, _ := kb.path(name); filepath.Base(path) != fi.Name() {
+			continue
+		}
+		info, _, err := kb.read(name)
+		if err != nil {
+			if name != fi.Name() {
+				errs[fi.Name()] = err
+			}
+			continue
+		}
+		res = append(res, info)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	if len(errs) > 0 {
+		return res, errs
+	}
+	return res, nil
+}
+
+// Get returns the public information about one key.
+func (kb fsKeybase) Get(name string) (Info, error) {
+	info, _, err := kb.read(name)
+	return info, err
+}
+
+// GetByAddress returns the public information about the key with the
+// given address, or with the given address in its key file, i.e. the
+// Ethereum address of secp256k1 keys. Keys from geth can be found by
+// the latter before their public key is known. It returns an error if
+// there is no such key.
+func (kb fsKeybase) GetByAddress(address crypto.Address) (Info, error) {
+	infos, err := kb.List()
+	if _, ok := err.(KeyFileErrors); err != nil && !ok {
+		return Info{}, err
+	}
+	hexAddress := hex.EncodeToString(address)
+	for _, info := range infos {
+		if bytes.Equal(info.Address(), address) {
+			return info, nil
+		}
+		key, err := parseWeb3Key([]byte(info.PrivKeyArmor))
+		if err == nil && key.address() != "" && key.address() == hexAddress {
+			return info, nil
+		}
+	}
+	return Info{}, errors.Errorf("No key with address %X", []byte(address))
+}
+
+// Sign signs the msg with the named key.
+// It returns an error if the key doesn't exist or the decryption fails.
+// A key below the KDF policy is upgraded on the way.
+func (kb fsKeybase) Sign(name, passphrase string, msg []byte) (sig crypto.Signature, pub crypto.PubKey, err error) {
+	priv, err := kb.unlock(name, passphrase)
+	if err != nil {
+		return
+	}
+	defer priv.Zeroize()
+	return signWith(priv, msg)
+}
+
+// SignEthereum signs an Ethereum digest with the named key, which must
+// be a secp256k1 key.
+func (kb fsKeybase) SignEthereum(name, passphrase string, hash []byte) (crypto.SignatureEthereum, error) {
+	priv, err := kb.unlock(name, passphrase)
+	if err != nil {
+		return crypto.SignatureEthereum{}, err
+	}
+	defer priv.Zeroize()
+	return signEthereumWith(name, priv, hash)
+}
+
+// unlock decrypts the named key, and upgrades it on a best effort
+// basis, like dbKeybase.unlock. The public key is added to files
+// without it the same way.
+// The caller must Zeroize the key after use.
+func (kb fsKeybase) unlock(name, passphrase string) (crypto.ZeroizablePrivKey, error) {
+	info, priv, err := kb.decrypt(name, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if info.WeakKDF {
+		kb.writeKey(priv, name, passphrase)
+	} else if info.PubKey == nil {
+		kb.addPubKey(name, priv.PubKey())
+	}
+	return priv, nil
+}
+
+// Upgrade re-encrypts the named key with the current KDF if its KDF is
+// below the policy. It returns true iff the key was upgraded.
+func (kb fsKeybase) Upgrade(name, passphrase string) (bool, error) {
+	info, priv, err := kb.decrypt(name, passphrase)
+	if err != nil {
+		return false, err
+	}
+	defer priv.Zeroize()
+	if !info.WeakKDF {
+		return false, nil
+	}
+	if _, err := kb.writeKey(priv, name, passphrase); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UpgradeAll upgrades all keys below the policy which have a passphrase
+// in passphrases, by name. It returns the names of the upgraded keys,
+// and stops at the first error.
+func (kb fsKeybase) UpgradeAll(passphrases map[string]string) ([]string, error) {
+	return upgradeAll(kb, passphrases)
+}
+
+// Export returns the key armored like dbKeybase.Export, but with the
+// key file as PrivKeyArmor. It can only be imported into a keybase
+// from NewFS.
+func (kb fsKeybase) Export(name string) (armor string, err error) {
+	info, err := kb.Get(name)
+	if err != nil {
+		return "", err
+	}
+	if info.PubKey == nil {
+		return "", errors.Errorf("Key %s has no public key until it's unlocked", name)
+	}
+	info = newInfo(name, info.PubKey, info.PrivKeyArmor)
+	return armorInfoBytes(info.bytes()), nil
+}
+
+// Import writes a key from Export to a new file. Ledger and remote keys
+// return an UnsupportedKindError.
+func (kb fsKeybase) Import(name string, armor string) (err error) {
+	if err := kb.checkNew(name); err != nil {
+		return err
+	}
+	infoBytes, err := unarmorInfoBytes(armor)
+	if err != nil {
+		return
+	}
+	info, err := readInfo(infoBytes)
+	if err != nil {
+		return
+	}
+	if info.Kind != KeyLocal && info.Kind != KeyOffline {
+		return UnsupportedKindError{Kind: info.Kind}
+	}
+	key, err := parseWeb3Key([]byte(info.PrivKeyArmor))
+	if err != nil {
+		return errors.Wrap(err, "Not a Web3 Secret Storage key")
+	}
+	if pub, err := key.pubKey(); err != nil || pub == nil || !pub.Equals(info.PubKey) {
+		return errors.New("Key file doesn't match the public key")
+	}
+	path, _ := kb.path(name)
+	return writeFileAtomic(path, []byte(info.PrivKeyArmor))
+}
+
+// Delete removes the key file forever, but we must present the
+// proper passphrase before deleting it (for security).
+// Offline keys have no passphrase, so any passphrase deletes them.
+func (kb fsKeybase) Delete(name, passphrase string) error {
+	info, err := kb.Get(name)
+	if err != nil {
+		return err
+	}
+	if info.Kind == KeyLocal {
+		_, priv, err := kb.decrypt(name, passphrase)
+		if err != nil {
+			return err
+		}
+		priv.Zeroize()
+	}
+	path, _ := kb.path(name)
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	return syncDir(kb.dir)
+}
+
+// Update changes the passphrase with which an already stored key is
+// encrypted.
+//
+// oldpass must be the current passphrase used for encryption,
+// newpass will be the only valid passphrase from this time forward.
+func (kb fsKeybase) Update(name, oldpass, newpass string) error {
+	_, key, err := kb.decrypt(name, oldpass)
+	if err != nil {
+		return err
+	}
+	defer key.Zeroize()
+
+	_, err = kb.writeKey(key, name, newpass)
+	return err
+}
+
+// decrypt returns the Info of the named key and decrypts it, without
+// upgrading it. The caller must Zeroize the key after use.
+func (kb fsKeybase) decrypt(name, passphrase string) (Info, crypto.ZeroizablePrivKey, error) {
+	info, key, err := kb.read(name)
+	if err != nil {
+		return Info{}, nil, err
+	}
+	if info.Kind != KeyLocal {
+		return Info{}, nil, OfflineKeyError{Name: name}
+	}
+	priv, err := key.decrypt(passphrase)
+	if err != nil {
+		return Info{}, nil, err
+	}
+	return info, priv, nil
+}
+
+// read returns the Info and parsed key file of name. The PubKey of key
+// files without a public key is nil.
+func (kb fsKeybase) read(name string) (Info, web3Key, error) {
+	path, err := kb.path(name)
+	if err != nil {
+		return Info{}, web3Key{}, err
+	}
+	bz, err := readKeyFile(path)
+	if os.IsNotExist(err) {
+		return Info{}, web3Key{}, errors.Errorf("No key with name %s", name)
+	} else if err != nil {
+		return Info{}, web3Key{}, err
+	}
+	key, err := parseWeb3Key(bz)
+	if err != nil {
+		return Info{}, web3Key{}, errors.Wrapf(err, "Reading %s", path)
+	}
+	pub, err := key.pubKey()
+	if err != nil {
+		return Info{}, web3Key{}, errors.Wrapf(err, "Reading %s", path)
+	}
+	if pub == nil && key.Crypto == nil {
+		return Info{}, web3Key{}, errors.Errorf("Reading %s: Key file has neither a public nor a private key", path)
+	}
+	if key.kind() == KeyOffline {
+		info := newOfflineInfo(name, pub)
+		info.PrivKeyArmor = string(bz)
+		return info, key, nil
+	}
+	info := newInfo(name, pub, string(bz))
+	kdf, _, err := key.kdf()
+	info.WeakKDF = err != nil || !kb.policy.Accepts(kdf)
+	return info, key, nil
+}
+
+// addPubKey adds pub to the key file of name, which has no public key.
+func (kb fsKeybase) addPubKey(name string, pub crypto.PubKey) error {
+	_, key, err := kb.read(name)
+	if err != nil {
+		return err
+	}
+	bz, err := key.withPubKey(pub)
+	if err != nil {
+		return err
+	}
+	path, _ := kb.path(name)
+	return writeFileAtomic(path, bz)
+}
+
+func (kb fsKeybase) writeKey(priv crypto.PrivKey, name, passphrase string) (Info, error) {
+	path, err := kb.path(name)
+	if err != nil {
+		return Info{}, err
+	}
+	bz, err := encryptWeb3Key(priv, passphrase, kb.policy.Current)
+	if err != nil {
+		return Info{}, err
+	}
+	if err := writeFileAtomic(path, bz); err != nil {
+		return Info{}, err
+	}
+	return newInfo(name, priv.PubKey(), string(bz)), nil
+}
+
+// checkNew returns an error if name is invalid or taken.
+func (kb fsKeybase) checkNew(name string) error {
+	path, err := kb.path(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		return errors.New("Cannot overwrite data for name " + name)
+	}
+	return nil
+}
+
+// path returns the file of the named key: name.json, unless only a
+// file name exists, like the files of geth.
+func (kb fsKeybase) path(name string) (string, error) {
+	if err := checkKeyName(name); err != nil {
+		return "", err
+	}
+	path := filepath.Join(kb.dir, name+fsKeyFileExt)
+	if _, err := os.Lstat(path); os.IsNotExist(err) && !strings.HasSuffix(name, fsKeyFileExt) {
+		other := filepath.Join(kb.dir, name)
+		if fi, err := os.Lstat(other); err == nil && fi.Mode().IsRegular() {
+			return other, nil
+		}
+	}
+	return path, nil
+}
+
+// checkKeyName returns an error if name can't be a file name in the
+// directory: it must not be empty, start with a dot (like temporary
+// files, which geth ignores too) or contain a path separator.
+func checkKeyName(name string) error {
+	if name == "" || name[0] == '.' || strings.ContainsAny(name, "/\\\x00") {
+		return errors.Errorf("Invalid key name %q", name)
+	}
+	return nil
+}
+
+//-------------------------------------
+
+// readKeyFile reads a key file, which must be accessible only by its
+// owner.
+func readKeyFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOwnerOnly(path, fi); err != nil {
+		return nil, err
+	}
+	bz, err := ioutil.ReadAll(io.LimitReader(f, fsMaxKeyFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(bz) > fsMaxKeyFileSize {
+		return nil, errors.Errorf("%s is too large for a key file", path)
+	}
+	return bz, nil
+}
+
+// writeFileAtomic writes data to path with mode 0600, so that path
+// holds either its old content or data, even after a crash.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // fails after the rename
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir persists the entries of dir.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// checkOwnerOnly returns an error if the group or others have any
+// permission on the file. Windows doesn't have Unix permissions.
+func checkOwnerOnly(path string, fi os.FileInfo) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if perm := fi.Mode().Perm(); perm&0077 != 0 {
+		return errors.Errorf("%s is accessible by other users (mode %04o)", path, perm)
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package keys_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/tendermint/go-crypto"
+	"github.com/tendermint/go-crypto/keys"
+	"github.com/tendermint/go-crypto/keys/words"
+)
+
+// fastFSPolicy keeps the tests fast.
+var fastFSPolicy = keys.KDFPolicy{
+	Current:   keys.ScryptKDF{N: 1024, R: 8, P: 1},
+	MinScrypt: keys.ScryptKDF{N: 1024, R: 8, P: 1},
+}
+
+func newTestFS(t *testing.T) (dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "fskeybase")
+	require.Nil(t, err, "%+v", err)
+	return filepath.Join(dir, "keys"), func() { os.RemoveAll(dir) }
+}
+
+func TestFSKeyManagement(t *testing.T) {
+	dir, cleanup := newTestFS(t)
+	defer cleanup()
+	cstore, err := keys.NewFSWithKDFPolicy(dir, words.MustLoadCodec("english"), fastFSPolicy)
+	require.Nil(t, err, "%+v", err)
+	fi, err := os.Stat(dir)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, os.FileMode(0700), fi.Mode().Perm())
+
+	n1, n2, p1, p2 := "personal", "business", "1234", "really-secure!@#$"
+	l, err := cstore.List()
+	require.Nil(t, err, "%+v", err)
+	assert.Empty(t, l)
+
+	i1, seed, err := cstore.Create(n1, p1, keys.AlgoSecp256k1)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, n1, i1.Name)
+	_, _, err = cstore.Create(n2, p2, keys.AlgoEd25519)
+	require.Nil(t, err, "%+v", err)
+	_, _, err = cstore.Create(n1, p1, keys.AlgoSecp256k1)
+	assert.NotNil(t, err, "names are unique")
+	for _, name := range []string{"", ".hidden", "a/b", "../up"} {
+		_, _, err = cstore.Create(name, p1, keys.AlgoEd25519)
+		assert.NotNil(t, err, name)
+	}
+
+	// one private file per key, and no temporary files
+	fis, err := ioutil.ReadDir(dir)
+	require.Nil(t, err, "%+v", err)
+	require.Equal(t, 2, len(fis))
+	assert.Equal(t, n2+".json", fis[0].Name())
+	assert.Equal(t, n1+".json", fis[1].Name())
+	for _, fi := range fis {
+		assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+	}
+
+	// other files are ignored, and malformed key files skipped
+	err = ioutil.WriteFile(filepath.Join(dir, "README"), []byte("hi"), 0600)
+	require.Nil(t, err, "%+v", err)
+	err = ioutil.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"version": 3}`), 0600)
+	require.Nil(t, err, "%+v", err)
+
+	l, err = cstore.List()
+	require.IsType(t, keys.KeyFileErrors{}, err)
+	assert.Contains(t, err.(keys.KeyFileErrors), "broken.json")
+	assert.Equal(t, 1, len(err.(keys.KeyFileErrors)))
+	require.Equal(t, 2, len(l))
+	assert.Equal(t, n2, l[0].Name)
+	assert.Equal(t, n1, l[1].Name)
+	assert.Equal(t, i1.PubKey, l[1].PubKey)
+	_, err = cstore.Get("broken")
+	assert.NotNil(t, err)
+	require.Nil(t, os.Remove(filepath.Join(dir, "broken.json")))
+	i, err := cstore.GetByAddress(i1.Address())
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, n1, i.Name)
+
+	// sign, also for Ethereum
+	msg := []byte("hello")
+	sig, pub, err := cstore.Sign(n1, p1, msg)
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, pub.VerifyBytes(msg, sig))
+	_, _, err = cstore.Sign(n1, p2, msg)
+	assert.NotNil(t, err)
+	hash := crypto.EthereumMessageHash(msg)
+	sigEth, err := cstore.SignEthereum(n1, p1, hash)
+	require.Nil(t, err, "%+v", err)
+	addr, err := i1.PubKey.(crypto.PubKeySecp256k1).EthereumAddress()
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, crypto.VerifyEthereumHash(addr, hash, sigEth))
+	_, err = cstore.SignEthereum(n2, p2, hash)
+	assert.NotNil(t, err)
+
+	// update the passphrase
+	err = cstore.Update(n1, p2, p1)
+	assert.NotNil(t, err)
+	err = cstore.Update(n1, p1, p2)
+	require.Nil(t, err, "%+v", err)
+	_, _, err = cstore.Sign(n1, p2, msg)
+	require.Nil(t, err, "%+v", err)
+
+	// export and import into another directory
+	armor, err := cstore.Export(n1)
+	require.Nil(t, err, "%+v", err)
+	dir2, cleanup2 := newTestFS(t)
+	defer cleanup2()
+	other, err := keys.NewFSWithKDFPolicy(dir2, words.MustLoadCodec("english"), fastFSPolicy)
+	require.Nil(t, err, "%+v", err)
+	err = other.Import("imported", armor)
+	require.Nil(t, err, "%+v", err)
+	_, _, err = other.Sign("imported", p2, msg)
+	require.Nil(t, err, "%+v", err)
+	err = other.Import("imported", armor)
+	assert.NotNil(t, err)
+
+	// recover from the seed
+	err = other.Delete("imported", p1)
+	assert.NotNil(t, err)
+	err = other.Delete("imported", p2)
+	require.Nil(t, err, "%+v", err)
+	i, err = other.Recover("recovered", p1, seed)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, i1.PubKey, i.PubKey)
+}
+
+func TestFSGethKeys(t *testing.T) {
+	dir, cleanup := newTestFS(t)
+	defer cleanup()
+	cstore, err := keys.NewFSWithKDFPolicy(dir, words.MustLoadCodec("english"), fastFSPolicy)
+	require.Nil(t, err, "%+v", err)
+
+	// a key file like geth writes it, without our public key
+	created, _, err := cstore.Create("tmp", "pass", keys.AlgoSecp256k1)
+	require.Nil(t, err, "%+v", err)
+	var file map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(created.PrivKeyArmor), &file))
+	delete(file, "tendermint")
+	bz, err := json.Marshal(file)
+	require.Nil(t, err, "%+v", err)
+	addr, err := created.PubKey.(crypto.PubKeySecp256k1).EthereumAddress()
+	require.Nil(t, err, "%+v", err)
+	name := "UTC--2018-05-01T10-00-00.000000000Z--" + hex.EncodeToString(addr[:])
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), bz, 0600))
+	require.Nil(t, os.Remove(filepath.Join(dir, "tmp.json")))
+
+	infos, err := cstore.List()
+	require.Nil(t, err, "%+v", err)
+	require.Equal(t, 1, len(infos))
+	assert.Equal(t, name, infos[0].Name)
+	assert.Equal(t, keys.KeyLocal, infos[0].Kind)
+	assert.Nil(t, infos[0].PubKey)
+	assert.Nil(t, infos[0].Address())
+	_, err = cstore.Export(name)
+	assert.NotNil(t, err, "no public key to export")
+	_, _, err = cstore.Create(name, "pass", keys.AlgoSecp256k1)
+	assert.NotNil(t, err, "names are unique")
+
+	info, err := cstore.GetByAddress(addr[:])
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, name, info.Name)
+
+	// the first unlock adds the public key, in place
+	_, _, err = cstore.Sign(name, "wrong", []byte("msg"))
+	assert.NotNil(t, err)
+	sig, pub, err := cstore.Sign(name, "pass", []byte("msg"))
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, created.PubKey, pub)
+	assert.True(t, pub.VerifyBytes([]byte("msg"), sig))
+	info, err = cstore.Get(name)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, created.PubKey, info.PubKey)
+	info, err = cstore.GetByAddress(created.PubKey.Address())
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, name, info.Name)
+	fis, err := ioutil.ReadDir(dir)
+	require.Nil(t, err, "%+v", err)
+	require.Equal(t, 1, len(fis))
+	assert.Equal(t, name, fis[0].Name())
+
+	// the address must match the key
+	file["address"] = hex.EncodeToString(make([]byte, 20))
+	bz, err = json.Marshal(file)
+	require.Nil(t, err, "%+v", err)
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "other"), bz, 0600))
+	_, _, err = cstore.Sign("other", "pass", []byte("msg"))
+	assert.NotNil(t, err)
+
+	err = cstore.Delete(name, "pass")
+	require.Nil(t, err, "%+v", err)
+	infos, err = cstore.List()
+	require.Nil(t, err, "%+v", err)
+	require.Equal(t, 1, len(infos))
+	assert.Equal(t, "other", infos[0].Name)
+}
+
+func TestFSPermissions(t *testing.T) {
+	dir, cleanup := newTestFS(t)
+	defer cleanup()
+	cstore, err := keys.NewFSWithKDFPolicy(dir, words.MustLoadCodec("english"), fastFSPolicy)
+	require.Nil(t, err, "%+v", err)
+	_, _, err = cstore.Create("key", "passphrase", keys.AlgoEd25519)
+	require.Nil(t, err, "%+v", err)
+
+	path := filepath.Join(dir, "key.json")
+	require.Nil(t, os.Chmod(path, 0644))
+	_, err = cstore.Get("key")
+	assert.NotNil(t, err, "readable by others")
+	_, err = cstore.List()
+	assert.NotNil(t, err)
+	require.Nil(t, os.Chmod(path, 0600))
+	_, err = cstore.Get("key")
+	assert.Nil(t, err, "%+v", err)
+
+	require.Nil(t, os.Chmod(dir, 0755))
+	_, err = keys.NewFSWithKDFPolicy(dir, words.MustLoadCodec("english"), fastFSPolicy)
+	assert.NotNil(t, err, "readable by others")
+
+	_, err = keys.NewFSWithKDFPolicy(dir, words.MustLoadCodec("english"), keys.DefaultKDFPolicy)
+	assert.NotNil(t, err, "argon2id isn't supported")
+}
+
+func TestFSKDFUpgrade(t *testing.T) {
+	dir, cleanup := newTestFS(t)
+	defer cleanup()
+	codec := words.MustLoadCodec("english")
+	old, err := keys.NewFSWithKDFPolicy(dir, codec, keys.KDFPolicy{Current: keys.PBKDF2KDF{Iterations: 1000}})
+	require.Nil(t, err, "%+v", err)
+	_, _, err = old.Create("a", "pa", keys.AlgoSecp256k1)
+	require.Nil(t, err, "%+v", err)
+	_, _, err = old.Create("b", "pb", keys.AlgoEd25519)
+	require.Nil(t, err, "%+v", err)
+
+	cstore, err := keys.NewFSWithKDFPolicy(dir, codec, fastFSPolicy)
+	require.Nil(t, err, "%+v", err)
+	infos, err := cstore.List()
+	require.Nil(t, err, "%+v", err)
+	require.Equal(t, 2, len(infos))
+	assert.True(t, infos[0].WeakKDF)
+	assert.True(t, infos[1].WeakKDF)
+
+	// signing upgrades on the way
+	_, _, err = cstore.Sign("a", "pa", []byte("msg"))
+	require.Nil(t, err, "%+v", err)
+	info, err := cstore.Get("a")
+	require.Nil(t, err, "%+v", err)
+	assert.False(t, info.WeakKDF)
+
+	upgraded, err := cstore.UpgradeAll(map[string]string{"a": "pa", "b": "pb"})
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, []string{"b"}, upgraded)
+	ok, err := cstore.Upgrade("b", "pb")
+	require.Nil(t, err, "%+v", err)
+	assert.False(t, ok)
+}
+
+func TestFSUnsupportedKinds(t *testing.T) {
+	dir, cleanup := newTestFS(t)
+	defer cleanup()
+	fs, err := keys.NewFSWithKDFPolicy(dir, words.MustLoadCodec("english"), fastFSPolicy)
+	require.Nil(t, err, "%+v", err)
+
+	path := keys.LedgerKey{Path: "44'/118'/0'/0/0"}
+	remote := keys.RemoteKey{Address: "tcp://10.0.0.1:26659", ID: "validator"}
+	_, err = fs.CreateLedger("nano", path)
+	assert.Equal(t, keys.UnsupportedKindError{Kind: keys.KeyLedger}, err)
+	_, err = fs.CreateRemote("signer", remote)
+	assert.Equal(t, keys.UnsupportedKindError{Kind: keys.KeyRemote}, err)
+
+	// nor can they be imported from a keybase which supports them
+	db, err := keys.New(dbm.NewMemDB(), words.MustLoadCodec("english")).
+		WithSigner(keys.KeyLedger, keys.MockSigner{Seed: []byte("ledger")})
+	require.Nil(t, err, "%+v", err)
+	db, err = db.WithSigner(keys.KeyRemote, keys.MockSigner{Seed: []byte("remote")})
+	require.Nil(t, err, "%+v", err)
+	_, err = db.CreateLedger("nano", path)
+	require.Nil(t, err, "%+v", err)
+	_, err = db.CreateRemote("signer", remote)
+	require.Nil(t, err, "%+v", err)
+	for name, kind := range map[string]keys.KeyKind{"nano": keys.KeyLedger, "signer": keys.KeyRemote} {
+		armor, err := db.Export(name)
+		require.Nil(t, err, "%+v", err)
+		err = fs.Import(name, armor)
+		assert.Equal(t, keys.UnsupportedKindError{Kind: kind}, err)
+	}
+
+	infos, err := fs.List()
+	require.Nil(t, err, "%+v", err)
+	assert.Empty(t, infos)
+}
@@ -0,0 +1,256 @@
+package keys
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+
+	crypto "github.com/tendermint/go-crypto"
+	"github.com/tendermint/go-crypto/keys/bcrypt"
+)
+
+// KDF derives the key which encrypts a private key from a passphrase.
+// The KDF's name and parameters are recorded in the armor headers of
+// each key, so keys encrypted with any supported KDF can be decrypted.
+// KDFs other than the built-in ones must be registered with
+// RegisterKDF before keys are encrypted with them.
+type KDF interface {
+	// Name is the "kdf" armor header.
+	Name() string
+	// Params returns the parameters to record in the armor headers.
+	Params() map[string]string
+	// DeriveKey returns a 32 byte key.
+	DeriveKey(passphrase, salt []byte) ([]byte, error)
+}
+
+const (
+	kdfBcrypt   = "bcrypt"
+	kdfScrypt   = "scrypt"
+	kdfArgon2id = "argon2id"
+	kdfPBKDF2   = "pbkdf2"
+
+	kdfSaltSize = 16
+	kdfKeySize  = 32
+
+	// kdfMaxMemory bounds the memory of scrypt and argon2id keys.
+	kdfMaxMemory = 1 << 30
+)
+
+// DefaultKDF is used by New. It follows the second recommended option
+// of RFC 9106.
+var DefaultKDF KDF = Argon2idKDF{Memory: 64 * 1024, Iterations: 3, Parallelism: 4}
+
+// KDFParser returns the KDF recorded in the armor headers of a key. It
+// must bound the parameters like the built-in KDFs do, see
+// kdfFromHeader.
+type KDFParser func(header map[string]string) (KDF, error)
+
+var kdfParsers = struct {
+	mtx    sync.RWMutex
+	byName map[string]KDFParser
+}{byName: map[string]KDFParser{}}
+
+// RegisterKDF lets keys encrypted with a custom KDF be decrypted,
+// imported and checked against a KDFPolicy. parse is called for keys
+// whose "kdf" header is name. It panics if name is a built-in KDF or
+// already registered, so it should be called from an init function.
+func RegisterKDF(name string, parse KDFParser) {
+	switch name {
+	case "", kdfBcrypt, kdfScrypt, kdfArgon2id, kdfPBKDF2:
+		panic(fmt.Sprintf("KDF name %q is reserved", name))
+	}
+	if parse == nil {
+		panic(fmt.Sprintf("KDF %s has no parser", name))
+	}
+	kdfParsers.mtx.Lock()
+	defer kdfParsers.mtx.Unlock()
+	if _, ok := kdfParsers.byName[name]; ok {
+		panic(fmt.Sprintf("KDF %s is already registered", name))
+	}
+	kdfParsers.byName[name] = parse
+}
+
+// kdfFromHeader returns the KDF recorded in the armor headers. The
+// parameters are bounded, so that a crafted armor or key file can't
+// exhaust memory or time before its MAC is checked: at most 1 GiB of
+// memory, and a few seconds on a current CPU.
+func kdfFromHeader(header map[string]string) (KDF, error) {
+	switch header["kdf"] {
+	case kdfBcrypt:
+		kdf := BcryptKDF{Cost: legacyBcryptCost}
+		// keys from before the cost was recorded have no header
+		if _, ok := header["cost"]; ok {
+			cost, err := parseKDFParam(header, "cost", 4, 16)
+			if err != nil {
+				return nil, err
+			}
+			kdf.Cost = int(cost)
+		}
+		return kdf, nil
+	case kdfScrypt:
+		n, err := parseKDFParam(header, "n", 2, 1<<24)
+		if err != nil {
+			return nil, err
+		}
+		if n&(n-1) != 0 {
+			return nil, fmt.Errorf("scrypt n must be a power of 2, got %d", n)
+		}
+		r, err := parseKDFParam(header, "r", 1, 64)
+		if err != nil {
+			return nil, err
+		}
+		p, err := parseKDFParam(header, "p", 1, 64)
+		if err != nil {
+			return nil, err
+		}
+		if 128*n*r*p > kdfMaxMemory {
+			return nil, fmt.Errorf("scrypt parameters need more than %d bytes: n=%d r=%d p=%d", kdfMaxMemory, n, r, p)
+		}
+		return ScryptKDF{N: int(n), R: int(r), P: int(p)}, nil
+	case kdfArgon2id:
+		m, err := parseKDFParam(header, "m", 8, kdfMaxMemory/1024) // in KiB
+		if err != nil {
+			return nil, err
+		}
+		t, err := parseKDFParam(header, "t", 1, 16)
+		if err != nil {
+			return nil, err
+		}
+		p, err := parseKDFParam(header, "p", 1, 255)
+		if err != nil {
+			return nil, err
+		}
+		return Argon2idKDF{Memory: uint32(m), Iterations: uint32(t), Parallelism: uint8(p)}, nil
+	case kdfPBKDF2:
+		c, err := parseKDFParam(header, "c", 1, 1<<22)
+		if err != nil {
+			return nil, err
+		}
+		return PBKDF2KDF{Iterations: int(c)}, nil
+	default:
+		kdfParsers.mtx.RLock()
+		parse, ok := kdfParsers.byName[header["kdf"]]
+		kdfParsers.mtx.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("Unrecognized KDF type: %v", header["kdf"])
+		}
+		return parse(header)
+	}
+}
+
+// parseKDFParam parses a parameter, which must be in [min, max].
+func parseKDFParam(header map[string]string, name string, min, max uint64) (uint64, error) {
+	s, ok := header[name]
+	if !ok {
+		return 0, fmt.Errorf("Missing KDF parameter %q", name)
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid KDF parameter %q: %v", name, err)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("KDF parameter %q out of range [%d, %d]: %d", name, min, max, v)
+	}
+	return v, nil
+}
+
+//-------------------------------------
+
+// legacyBcryptCost is the cost of keys from before the cost was
+// recorded in the armor headers.
+const legacyBcryptCost = 12
+
+// BcryptKDF is the original KDF: SHA256 of the bcrypt hash.
+// Use it only to read old keys.
+type BcryptKDF struct {
+	Cost int
+}
+
+func (kdf BcryptKDF) Name() string { return kdfBcrypt }
+
+func (kdf BcryptKDF) Params() map[string]string {
+	return map[string]string{"cost": strconv.Itoa(kdf.Cost)}
+}
+
+func (kdf BcryptKDF) DeriveKey(passphrase, salt []byte) ([]byte, error) {
+	key, err := bcrypt.GenerateFromPassword(salt, passphrase, kdf.Cost)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.Zeroize(key)
+	return crypto.Sha256(key), nil // Get 32 bytes
+}
+
+//-------------------------------------
+
+// ScryptKDF is scrypt (RFC 7914) with cost N, block size R and
+// parallelism P.
+type ScryptKDF struct {
+	N, R, P int
+}
+
+func (kdf ScryptKDF) Name() string { return kdfScrypt }
+
+func (kdf ScryptKDF) Params() map[string]string {
+	return map[string]string{
+		"n": strconv.Itoa(kdf.N),
+		"r": strconv.Itoa(kdf.R),
+		"p": strconv.Itoa(kdf.P),
+	}
+}
+
+func (kdf ScryptKDF) DeriveKey(passphrase, salt []byte) ([]byte, error) {
+	return scrypt.Key(passphrase, salt, kdf.N, kdf.R, kdf.P, kdfKeySize)
+}
+
+//-------------------------------------
+
+// Argon2idKDF is Argon2id (RFC 9106) with Memory in KiB.
+type Argon2idKDF struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+func (kdf Argon2idKDF) Name() string { return kdfArgon2id }
+
+func (kdf Argon2idKDF) Params() map[string]string {
+	return map[string]string{
+		"m": strconv.FormatUint(uint64(kdf.Memory), 10),
+		"t": strconv.FormatUint(uint64(kdf.Iterations), 10),
+		"p": strconv.FormatUint(uint64(kdf.Parallelism), 10),
+	}
+}
+
+func (kdf Argon2idKDF) DeriveKey(passphrase, salt []byte) ([]byte, error) {
+	if kdf.Iterations < 1 || kdf.Parallelism < 1 {
+		return nil, fmt.Errorf("Invalid argon2id parameters %+v", kdf)
+	}
+	return argon2.IDKey(passphrase, salt, kdf.Iterations, kdf.Memory, kdf.Parallelism, kdfKeySize), nil
+}
+
+//-------------------------------------
+
+// PBKDF2KDF is PBKDF2 (RFC 8018) with HMAC-SHA256, as used by some
+// Web3 Secret Storage key files, see NewFS.
+type PBKDF2KDF struct {
+	Iterations int
+}
+
+func (kdf PBKDF2KDF) Name() string { return kdfPBKDF2 }
+
+func (kdf PBKDF2KDF) Params() map[string]string {
+	return map[string]string{"c": strconv.Itoa(kdf.Iterations)}
+}
+
+func (kdf PBKDF2KDF) DeriveKey(passphrase, salt []byte) ([]byte, error) {
+	if kdf.Iterations < 1 {
+		return nil, fmt.Errorf("Invalid pbkdf2 parameters %+v", kdf)
+	}
+	return pbkdf2.Key(passphrase, salt, kdf.Iterations, kdfKeySize, sha256.New), nil
+}
@@ -2,7 +2,6 @@ package keys
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/pkg/errors"
 	crypto "github.com/tendermint/go-crypto"
@@ -13,15 +12,58 @@ import (
 // dbKeybase combines encyption and storage implementation to provide
 // a full-featured key manager
 type dbKeybase struct {
-	db    dbm.DB
-	codec words.Codec
+	db      dbm.DB
+	codec   words.Codec
+	policy  KDFPolicy
+	signers map[KeyKind]SignerBackend
 }
 
+// New returns a keybase with the DefaultKDFPolicy.
 func New(db dbm.DB, codec words.Codec) dbKeybase {
+	return NewWithKDFPolicy(db, codec, DefaultKDFPolicy)
+}
+
+// NewWithKDF returns a keybase which encrypts new and updated keys
+// with kdf. It accepts keys encrypted with any KDF, so it never
+// upgrades (or downgrades) keys.
+func NewWithKDF(db dbm.DB, codec words.Codec, kdf KDF) dbKeybase {
+	return NewWithKDFPolicy(db, codec, KDFPolicy{
+		Current:             kdf,
+		MinArgon2id:         Argon2idKDF{Memory: 1, Iterations: 1, Parallelism: 1},
+		MinScrypt:           ScryptKDF{N: 1, R: 1, P: 1},
+		MinBcryptCost:       1,
+		MinPBKDF2Iterations: 1,
+	})
+}
+
+// NewWithKDFPolicy returns a keybase which encrypts keys with the
+// policy's current KDF, and upgrades keys below the policy.
+func NewWithKDFPolicy(db dbm.DB, codec words.Codec, policy KDFPolicy) dbKeybase {
 	return dbKeybase{
-		db:    db,
-		codec: codec,
+		db:     db,
+		codec:  codec,
+		policy: policy,
+	}
+}
+
+// WithSigner returns a copy of the keybase which signs with keys of
+// the given kind, KeyLedger or KeyRemote, through backend. Other kinds
+// return an UnsupportedKindError.
+func (kb dbKeybase) WithSigner(kind KeyKind, backend SignerBackend) (dbKeybase, error) {
+	if kind != KeyLedger && kind != KeyRemote {
+		return dbKeybase{}, UnsupportedKindError{Kind: kind}
+	}
+	if backend == nil {
+		return dbKeybase{}, errors.Errorf("No SignerBackend for %v keys", kind)
+	}
+	signers := map[KeyKind]SignerBackend{kind: backend}
+	for k, b := range kb.signers {
+		if k != kind {
+			signers[k] = b
+		}
 	}
+	kb.signers = signers
+	return kb, nil
 }
 
 var _ Keybase = dbKeybase{}
@@ -32,53 +74,74 @@ var _ Keybase = dbKeybase{}
 // generate a key for the given algo type, or if another key is
 // already stored under the same name.
 func (kb dbKeybase) Create(name, passphrase string, algo CryptoAlgo) (Info, string, error) {
-	// NOTE: secret is SHA256 hashed by secp256k1 and ed25519.
-	// 16 byte secret corresponds to 12 BIP39 words.
-	// XXX: Ledgers use 24 words now - should we ?
-	secret := crypto.CRandBytes(16)
-	priv, err := generate(algo, secret)
+	priv, seed, err := newSeedKey(kb.codec, algo)
 	if err != nil {
 		return Info{}, "", err
 	}
 
 	// encrypt and persist the key
-	info := kb.writeKey(priv, name, passphrase)
-
-	// we append the type byte to the serialized secret to help with
-	// recovery
-	// ie [secret] = [type] + [secret]
-	typ := cryptoAlgoToByte(algo)
-	secret = append([]byte{typ}, secret...)
-
-	// return the mnemonic phrase
-	words, err := kb.codec.BytesToWords(secret)
-	seed := strings.Join(words, " ")
-	return info, seed, err
+	info, err := kb.writeKey(priv, name, passphrase)
+	if err != nil {
+		return Info{}, "", err
+	}
+	return info, seed, nil
 }
 
 // Recover converts a seedphrase to a private key and persists it,
 // encrypted with the given passphrase.  Functions like Create, but
 // seedphrase is input not output.
 func (kb dbKeybase) Recover(name, passphrase, seedphrase string) (Info, error) {
-	words := strings.Split(strings.TrimSpace(seedphrase), " ")
-	secret, err := kb.codec.WordsToBytes(words)
+	priv, err := recoverSeedKey(kb.codec, seedphrase)
 	if err != nil {
 		return Info{}, err
 	}
 
-	// secret is comprised of the actual secret with the type
-	// appended.
-	// ie [secret] = [type] + [secret]
-	typ, secret := secret[0], secret[1:]
-	algo := byteToCryptoAlgo(typ)
-	priv, err := generate(algo, secret)
-	if err != nil {
+	// encrypt and persist key.
+	return kb.writeKey(priv, name, passphrase)
+}
+
+// CreateOffline stores a watch-only key with the public key pub. It
+// returns an error if pub is nil or another key is already stored under
+// the same name.
+func (kb dbKeybase) CreateOffline(name string, pub crypto.PubKey) (Info, error) {
+	if len(kb.db.Get(infoKey(name))) > 0 {
+		return Info{}, errors.New("Cannot overwrite data for name " + name)
+	}
+	info := newOfflineInfo(name, pub)
+	if err := info.validate(); err != nil {
 		return Info{}, err
 	}
+	kb.db.SetSync(infoKey(name), info.bytes())
+	return info, nil
+}
 
-	// encrypt and persist key.
-	public := kb.writeKey(priv, name, passphrase)
-	return public, err
+// CreateLedger stores the key at the given location on a Ledger. Its
+// public key is read through the SignerBackend for KeyLedger.
+func (kb dbKeybase) CreateLedger(name string, key LedgerKey) (Info, error) {
+	return kb.createExternal(Info{Name: name, Kind: KeyLedger, Ledger: &key})
+}
+
+// CreateRemote stores the key at the given location on a remote
+// signer. Its public key is read through the SignerBackend for
+// KeyRemote.
+func (kb dbKeybase) CreateRemote(name string, key RemoteKey) (Info, error) {
+	return kb.createExternal(Info{Name: name, Kind: KeyRemote, Remote: &key})
+}
+
+func (kb dbKeybase) createExternal(info Info) (Info, error) {
+	if len(kb.db.Get(infoKey(info.Name))) > 0 {
+		return Info{}, errors.New("Cannot overwrite data for name " + info.Name)
+	}
+	backend, err := signerFor(kb.signers, info)
+	if err != nil {
+		return Info{}, err
+	}
+	info.PubKey, err = backend.PubKey(info)
+	if err != nil {
+		return Info{}, errors.Wrapf(err, "Reading the public key of %s", info.Name)
+	}
+	kb.db.SetSync(infoKey(info.Name), info.bytes())
+	return info, nil
 }
 
 // List returns the keys from storage in alphabetical order.
@@ -92,7 +155,7 @@ func (kb dbKeybase) List() ([]Info, error) {
 		if err != nil {
 			return nil, err
 		}
-		res = append(res, info)
+		res = append(res, kb.flagWeakKDF(info))
 	}
 	return res, nil
 }
@@ -100,23 +163,116 @@ func (kb dbKeybase) List() ([]Info, error) {
 // Get returns the public information about one key.
 func (kb dbKeybase) Get(name string) (Info, error) {
 	bs := kb.db.Get(infoKey(name))
-	return readInfo(bs)
+	info, err := readInfo(bs)
+	if err != nil {
+		return info, err
+	}
+	return kb.flagWeakKDF(info), nil
+}
+
+// flagWeakKDF sets info.WeakKDF if the key's KDF is below the policy.
+func (kb dbKeybase) flagWeakKDF(info Info) Info {
+	info.WeakKDF = info.Kind == KeyLocal && !kb.policy.acceptsArmor(info.PrivKeyArmor)
+	return info
+}
+
+// decryptInfo decrypts the private key of info, which must be a local key.
+// The caller must Zeroize the key after use.
+func decryptInfo(info Info, passphrase string) (crypto.ZeroizablePrivKey, error) {
+	switch info.Kind {
+	case KeyLocal:
+		return unarmorDecryptPrivKey(info.PrivKeyArmor, passphrase)
+	case KeyOffline:
+		return nil, OfflineKeyError{Name: info.Name}
+	default:
+		return nil, errors.Errorf("Key %s is a %v key, which has no passphrase", info.Name, info.Kind)
+	}
+}
+
+// GetByAddress returns the public information about the key with the
+// given address. It returns an error if there is no such key.
+func (kb dbKeybase) GetByAddress(address crypto.Address) (Info, error) {
+	return getByAddress(kb, address)
 }
 
 // Sign signs the msg with the named key.
 // It returns an error if the key doesn't exist or the decryption fails.
+// A key below the KDF policy is upgraded on the way.
+// Ledger and remote keys are signed by their SignerBackend, which
+// ignores the passphrase.
 func (kb dbKeybase) Sign(name, passphrase string, msg []byte) (sig crypto.Signature, pub crypto.PubKey, err error) {
 	info, err := kb.Get(name)
 	if err != nil {
 		return
 	}
-	priv, err := unarmorDecryptPrivKey(info.PrivKeyArmor, passphrase)
+	if info.Kind != KeyLocal {
+		return signWithBackend(kb.signers, info, msg)
+	}
+	priv, err := kb.unlock(info, passphrase)
 	if err != nil {
 		return
 	}
-	sig = priv.Sign(msg)
-	pub = priv.PubKey()
-	return
+	defer priv.Zeroize()
+	return signWith(priv, msg)
+}
+
+// SignEthereum signs an Ethereum digest with the named key, which must
+// be a secp256k1 key. Only sign digests computed from data you have
+// checked: a digest may as well be the hash of a transaction.
+func (kb dbKeybase) SignEthereum(name, passphrase string, hash []byte) (crypto.SignatureEthereum, error) {
+	info, err := kb.Get(name)
+	if err != nil {
+		return crypto.SignatureEthereum{}, err
+	}
+	priv, err := kb.unlock(info, passphrase)
+	if err != nil {
+		return crypto.SignatureEthereum{}, err
+	}
+	defer priv.Zeroize()
+	return signEthereumWith(name, priv, hash)
+}
+
+// unlock decrypts the key of info. If its KDF is below the policy, the
+// key is re-encrypted with the current KDF. That is best effort: the
+// key is returned even if the upgrade fails.
+// The caller must Zeroize the key after use.
+func (kb dbKeybase) unlock(info Info, passphrase string) (crypto.ZeroizablePrivKey, error) {
+	priv, err := decryptInfo(info, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if info.WeakKDF {
+		kb.writeKey(priv, info.Name, passphrase)
+	}
+	return priv, nil
+}
+
+// Upgrade re-encrypts the named key with the current KDF if its KDF is
+// below the policy. It returns true iff the key was upgraded.
+func (kb dbKeybase) Upgrade(name, passphrase string) (bool, error) {
+	info, err := kb.Get(name)
+	if err != nil {
+		return false, err
+	}
+	priv, err := decryptInfo(info, passphrase)
+	if err != nil {
+		return false, err
+	}
+	defer priv.Zeroize()
+	if !info.WeakKDF {
+		return false, nil
+	}
+	if _, err := kb.writeKey(priv, name, passphrase); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UpgradeAll upgrades all keys below the policy which have a passphrase
+// in passphrases, by name. It returns the names of the upgraded keys,
+// and stops at the first error.
+func (kb dbKeybase) UpgradeAll(passphrases map[string]string) ([]string, error) {
+	return upgradeAll(kb, passphrases)
 }
 
 func (kb dbKeybase) Export(name string) (armor string, err error) {
@@ -136,21 +292,32 @@ func (kb dbKeybase) Import(name string, armor string) (err error) {
 	if err != nil {
 		return
 	}
+	info, err := readInfo(infoBytes)
+	if err != nil {
+		return
+	}
+	if err := info.validate(); err != nil {
+		return err
+	}
 	kb.db.Set(infoKey(name), infoBytes)
 	return nil
 }
 
 // Delete removes key forever, but we must present the
 // proper passphrase before deleting it (for security).
+// Only local keys have a passphrase: any passphrase deletes the others.
 func (kb dbKeybase) Delete(name, passphrase string) error {
 	// verify we have the proper password before deleting
 	info, err := kb.Get(name)
 	if err != nil {
 		return err
 	}
-	_, err = unarmorDecryptPrivKey(info.PrivKeyArmor, passphrase)
-	if err != nil {
-		return err
+	if info.Kind == KeyLocal {
+		priv, err := decryptInfo(info, passphrase)
+		if err != nil {
+			return err
+		}
+		priv.Zeroize()
 	}
 	kb.db.DeleteSync(infoKey(name))
 	return nil
@@ -166,35 +333,28 @@ func (kb dbKeybase) Update(name, oldpass, newpass string) error {
 	if err != nil {
 		return err
 	}
-	key, err := unarmorDecryptPrivKey(info.PrivKeyArmor, oldpass)
+	key, err := decryptInfo(info, oldpass)
 	if err != nil {
 		return err
 	}
+	defer key.Zeroize()
 
-	kb.writeKey(key, name, newpass)
-	return nil
+	_, err = kb.writeKey(key, name, newpass)
+	return err
 }
-func (kb dbKeybase) writeKey(priv crypto.PrivKey, name, passphrase string) Info {
+
+func (kb dbKeybase) writeKey(priv crypto.PrivKey, name, passphrase string) (Info, error) {
 	// generate the encrypted privkey
-	privArmor := encryptArmorPrivKey(priv, passphrase)
+	privArmor, err := encryptArmorPrivKey(priv, passphrase, kb.policy.Current)
+	if err != nil {
+		return Info{}, err
+	}
 	// make Info
 	info := newInfo(name, priv.PubKey(), privArmor)
 
 	// write them both
 	kb.db.SetSync(infoKey(name), info.bytes())
-	return info
-}
-
-func generate(algo CryptoAlgo, secret []byte) (crypto.PrivKey, error) {
-	switch algo {
-	case AlgoEd25519:
-		return crypto.GenPrivKeyEd25519FromSecret(secret), nil
-	case AlgoSecp256k1:
-		return crypto.GenPrivKeySecp256k1FromSecret(secret), nil
-	default:
-		err := errors.Errorf("Cannot generate keys for algorithm: %s", algo)
-		return nil, err
-	}
+	return info, nil
 }
 
 func infoKey(name string) []byte {
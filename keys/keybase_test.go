@@ -1,12 +1,15 @@
 package keys_test
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	amino "github.com/tendermint/go-amino"
 	dbm "github.com/tendermint/tmlibs/db"
 
 	"github.com/tendermint/go-crypto"
@@ -326,6 +329,33 @@ func TestSeedPhrase(t *testing.T) {
 	assert.Equal(t, info.PubKey, newInfo.PubKey)
 }
 
+// TestSchnorrKeys makes sure schnorr keys can be created, used
+// and recovered from their seed phrase
+func TestSchnorrKeys(t *testing.T) {
+
+	// make the storage with reasonable defaults
+	cstore := keys.New(
+		dbm.NewMemDB(),
+		words.MustLoadCodec("english"),
+	)
+
+	n1, n2, p := "schnorr", "schnorr-again", "1234"
+	info, seed, err := cstore.Create(n1, p, keys.AlgoSchnorr)
+	require.Nil(t, err, "%+v", err)
+	_, ok := info.PubKey.(crypto.PubKeySchnorr)
+	require.True(t, ok)
+
+	msg := []byte("sign me with schnorr")
+	sig, pub, err := cstore.Sign(n1, p, msg)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, info.PubKey, pub)
+	assert.True(t, pub.VerifyBytes(msg, sig))
+
+	recovered, err := cstore.Recover(n2, p, seed)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, info.PubKey, recovered.PubKey)
+}
+
 func ExampleNew() {
 	// Select the encryption and storage for your cryptostore
 	cstore := keys.New(
@@ -378,3 +408,465 @@ func ExampleNew() {
 	// Carl
 	// signed by Bob
 }
+
+func TestGetByBech32Address(t *testing.T) {
+
+	// make the storage with reasonable defaults
+	cstore := keys.New(
+		dbm.NewMemDB(),
+		words.MustLoadCodec("english"),
+	)
+
+	p := "1234"
+	alice, _, err := cstore.Create("alice", p, keys.AlgoEd25519)
+	require.Nil(t, err, "%+v", err)
+	bob, _, err := cstore.Create("bob", p, keys.AlgoSecp256k1)
+	require.Nil(t, err, "%+v", err)
+
+	for _, info := range []keys.Info{alice, bob} {
+		bech, err := info.Bech32Address("tm")
+		require.Nil(t, err, "%+v", err)
+
+		addr, err := crypto.AddressFromBech32("tm", bech)
+		require.Nil(t, err, "%+v", err)
+		found, err := cstore.GetByAddress(addr)
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, info.Name, found.Name)
+		assert.Equal(t, info.PubKey, found.PubKey)
+	}
+
+	_, err = cstore.GetByAddress(crypto.GenPrivKeyEd25519().PubKey().Address())
+	assert.NotNil(t, err)
+}
+
+func TestSignEthereum(t *testing.T) {
+	cstore := keys.New(
+		dbm.NewMemDB(),
+		words.MustLoadCodec("english"),
+	)
+
+	p := "1234"
+	bob, _, err := cstore.Create("bob", p, keys.AlgoSecp256k1)
+	require.Nil(t, err, "%+v", err)
+	_, _, err = cstore.Create("alice", p, keys.AlgoEd25519)
+	require.Nil(t, err, "%+v", err)
+
+	addr, err := bob.PubKey.(crypto.PubKeySecp256k1).EthereumAddress()
+	require.Nil(t, err, "%+v", err)
+
+	msg := []byte("bridge transfer #1")
+	sig, err := cstore.SignEthereum("bob", p, crypto.EthereumMessageHash(msg))
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, crypto.VerifyEthereumMessage(addr, msg, sig))
+
+	_, err = cstore.SignEthereum("bob", "wrong", crypto.EthereumMessageHash(msg))
+	assert.NotNil(t, err)
+	_, err = cstore.SignEthereum("alice", p, crypto.EthereumMessageHash(msg))
+	assert.NotNil(t, err)
+}
+
+func TestKDFUpgrade(t *testing.T) {
+	db := dbm.NewMemDB()
+	codec := words.MustLoadCodec("english")
+	cheap := keys.Argon2idKDF{Memory: 64, Iterations: 1, Parallelism: 1}
+	policy := keys.KDFPolicy{Current: cheap, MinArgon2id: cheap}
+
+	// keys from before the policy
+	old := keys.NewWithKDF(db, codec, keys.BcryptKDF{Cost: 4})
+	p := "1234"
+	for _, name := range []string{"alice", "bob", "carl"} {
+		_, _, err := old.Create(name, p, keys.AlgoEd25519)
+		require.Nil(t, err, "%+v", err)
+	}
+	infos, err := old.List()
+	require.Nil(t, err, "%+v", err)
+	for _, info := range infos {
+		assert.False(t, info.WeakKDF, info.Name)
+	}
+
+	cstore := keys.NewWithKDFPolicy(db, codec, policy)
+	infos, err = cstore.List()
+	require.Nil(t, err, "%+v", err)
+	require.Len(t, infos, 3)
+	for _, info := range infos {
+		assert.True(t, info.WeakKDF, info.Name)
+	}
+
+	// signing upgrades the key
+	_, _, err = cstore.Sign("alice", p, []byte("msg"))
+	require.Nil(t, err, "%+v", err)
+	alice, err := cstore.Get("alice")
+	require.Nil(t, err, "%+v", err)
+	assert.False(t, alice.WeakKDF)
+	_, header, _, err := crypto.DecodeArmor(alice.PrivKeyArmor)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, "argon2id", header["kdf"])
+	// and the old keybase can still use it
+	_, _, err = old.Sign("alice", p, []byte("msg"))
+	assert.Nil(t, err, "%+v", err)
+
+	// explicit upgrades
+	_, err = cstore.Upgrade("bob", "wrong")
+	assert.NotNil(t, err)
+	upgraded, err := cstore.Upgrade("bob", p)
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, upgraded)
+	upgraded, err = cstore.Upgrade("bob", p)
+	require.Nil(t, err, "%+v", err)
+	assert.False(t, upgraded)
+
+	// bulk upgrades skip keys without a passphrase and strong keys
+	names, err := cstore.UpgradeAll(map[string]string{"alice": p, "bob": p})
+	require.Nil(t, err, "%+v", err)
+	assert.Empty(t, names)
+	_, err = cstore.UpgradeAll(map[string]string{"carl": "wrong"})
+	assert.NotNil(t, err)
+	names, err = cstore.UpgradeAll(map[string]string{"carl": p})
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, []string{"carl"}, names)
+
+	infos, err = cstore.List()
+	require.Nil(t, err, "%+v", err)
+	for _, info := range infos {
+		assert.False(t, info.WeakKDF, info.Name)
+		assertPassword(t, cstore, info.Name, p, "wrong")
+	}
+}
+
+func TestKDFPolicy(t *testing.T) {
+	policy := keys.DefaultKDFPolicy
+	assert.True(t, policy.Accepts(keys.DefaultKDF))
+	assert.True(t, policy.Accepts(keys.Argon2idKDF{Memory: 128 * 1024, Iterations: 3, Parallelism: 1}))
+	assert.False(t, policy.Accepts(keys.Argon2idKDF{Memory: 32 * 1024, Iterations: 3, Parallelism: 4}))
+	assert.False(t, policy.Accepts(keys.Argon2idKDF{Memory: 64 * 1024, Iterations: 2, Parallelism: 4}))
+	assert.True(t, policy.Accepts(keys.ScryptKDF{N: 1 << 15, R: 8, P: 1}))
+	assert.False(t, policy.Accepts(keys.ScryptKDF{N: 1 << 14, R: 8, P: 1}))
+	assert.False(t, policy.Accepts(keys.BcryptKDF{Cost: 12}))
+	assert.False(t, policy.Accepts(keys.PBKDF2KDF{Iterations: 1 << 20}))
+
+	policy.MinBcryptCost = 12
+	assert.True(t, policy.Accepts(keys.BcryptKDF{Cost: 12}))
+	assert.False(t, policy.Accepts(keys.BcryptKDF{Cost: 11}))
+
+	// only the current KDF, without minimums
+	policy = keys.KDFPolicy{Current: keys.ScryptKDF{N: 1024, R: 8, P: 1}}
+	assert.True(t, policy.Accepts(keys.ScryptKDF{N: 1024, R: 8, P: 1}))
+	assert.False(t, policy.Accepts(keys.ScryptKDF{N: 1 << 20, R: 8, P: 1}))
+	assert.False(t, policy.Accepts(keys.DefaultKDF))
+}
+
+// roundsKDF is a custom KDF, iterated sha256. Don't use it for real
+// keys.
+type roundsKDF struct{ Rounds int }
+
+func (kdf roundsKDF) Name() string { return "test-rounds" }
+
+func (kdf roundsKDF) Params() map[string]string {
+	return map[string]string{"rounds": strconv.Itoa(kdf.Rounds)}
+}
+
+func (kdf roundsKDF) DeriveKey(passphrase, salt []byte) ([]byte, error) {
+	key := sha256.Sum256(append(salt, passphrase...))
+	for i := 1; i < kdf.Rounds; i++ {
+		key = sha256.Sum256(key[:])
+	}
+	return key[:], nil
+}
+
+// unregisteredKDF is never registered.
+type unregisteredKDF struct{ roundsKDF }
+
+func (kdf unregisteredKDF) Name() string { return "test-unregistered" }
+
+func init() {
+	keys.RegisterKDF("test-rounds", func(header map[string]string) (keys.KDF, error) {
+		rounds, err := strconv.Atoi(header["rounds"])
+		if err != nil || rounds < 1 || rounds > 1<<16 {
+			return nil, fmt.Errorf("Invalid rounds: %q", header["rounds"])
+		}
+		return roundsKDF{Rounds: rounds}, nil
+	})
+}
+
+func TestCustomKDF(t *testing.T) {
+	codec := words.MustLoadCodec("english")
+	custom := roundsKDF{Rounds: 100}
+	cstore := keys.NewWithKDFPolicy(dbm.NewMemDB(), codec, keys.KDFPolicy{Current: custom})
+	p := "1234"
+
+	info, _, err := cstore.Create("alice", p, keys.AlgoEd25519)
+	require.Nil(t, err, "%+v", err)
+	assert.False(t, info.WeakKDF)
+	_, header, _, err := crypto.DecodeArmor(info.PrivKeyArmor)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, "test-rounds", header["kdf"])
+	assert.Equal(t, "100", header["rounds"])
+
+	// the key goes through Export and Import to a keybase with another
+	// KDF, and can still be used
+	armor, err := cstore.Export("alice")
+	require.Nil(t, err, "%+v", err)
+	other := keys.NewWithKDF(dbm.NewMemDB(), codec, keys.ScryptKDF{N: 1024, R: 8, P: 1})
+	err = other.Import("alice", armor)
+	require.Nil(t, err, "%+v", err)
+	imported, err := other.Get("alice")
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, info.PubKey, imported.PubKey)
+	// a custom KDF is weak unless it's the current one
+	assert.True(t, imported.WeakKDF)
+	assert.False(t, keys.DefaultKDFPolicy.Accepts(custom))
+	_, _, err = other.Sign("alice", "wrong", []byte("msg"))
+	assert.NotNil(t, err)
+	sig, pub, err := other.Sign("alice", p, []byte("msg"))
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, pub.VerifyBytes([]byte("msg"), sig))
+	imported, err = other.Get("alice")
+	require.Nil(t, err, "%+v", err)
+	assert.False(t, imported.WeakKDF, "upgraded by Sign")
+
+	// and back
+	armor, err = other.Export("alice")
+	require.Nil(t, err, "%+v", err)
+	err = cstore.Import("alice2", armor)
+	require.Nil(t, err, "%+v", err)
+	assertPassword(t, cstore, "alice2", p, "wrong")
+	alice2, err := cstore.Get("alice2")
+	require.Nil(t, err, "%+v", err)
+	_, header, _, err = crypto.DecodeArmor(alice2.PrivKeyArmor)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, "test-rounds", header["kdf"])
+
+	// keys aren't written with a KDF they can't be read back with
+	unregistered := keys.NewWithKDF(dbm.NewMemDB(), codec, unregisteredKDF{custom})
+	_, _, err = unregistered.Create("bob", p, keys.AlgoEd25519)
+	assert.NotNil(t, err)
+	_, err = unregistered.List()
+	require.Nil(t, err, "%+v", err)
+
+	assert.Panics(t, func() {
+		keys.RegisterKDF("test-rounds", func(map[string]string) (keys.KDF, error) { return custom, nil })
+	}, "names are unique")
+	assert.Panics(t, func() {
+		keys.RegisterKDF("scrypt", func(map[string]string) (keys.KDF, error) { return custom, nil })
+	}, "built-in names are reserved")
+}
+
+func TestOfflineKeys(t *testing.T) {
+	dir, cleanup := newTestFS(t)
+	defer cleanup()
+	fs, err := keys.NewFSWithKDFPolicy(dir, words.MustLoadCodec("english"), fastFSPolicy)
+	require.Nil(t, err, "%+v", err)
+	db := keys.NewWithKDF(dbm.NewMemDB(), words.MustLoadCodec("english"), keys.ScryptKDF{N: 1024, R: 8, P: 1})
+
+	for _, cstore := range []keys.Keybase{db, fs} {
+		hot, _, err := cstore.Create("hot", "1234", keys.AlgoSecp256k1)
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, keys.KeyLocal, hot.Kind)
+
+		pub := crypto.GenPrivKeySecp256k1().PubKey()
+		cold, err := cstore.CreateOffline("cold", pub)
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, keys.KeyOffline, cold.Kind)
+		_, err = cstore.CreateOffline("cold", pub)
+		assert.NotNil(t, err, "names are unique")
+		_, err = cstore.CreateOffline("hot", pub)
+		assert.NotNil(t, err, "names are unique")
+		_, err = cstore.CreateOffline("nil", nil)
+		assert.NotNil(t, err, "an offline key needs a public key")
+
+		infos, err := cstore.List()
+		require.Nil(t, err, "%+v", err)
+		require.Equal(t, 2, len(infos))
+		assert.Equal(t, "cold", infos[0].Name)
+		assert.Equal(t, keys.KeyOffline, infos[0].Kind)
+		assert.Equal(t, pub, infos[0].PubKey)
+		assert.False(t, infos[0].WeakKDF)
+		assert.Equal(t, keys.KeyLocal, infos[1].Kind)
+		info, err := cstore.GetByAddress(pub.Address())
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, "cold", info.Name)
+
+		// there is no private key to use
+		_, _, err = cstore.Sign("cold", "", []byte("msg"))
+		assert.Equal(t, keys.OfflineKeyError{Name: "cold"}, err)
+		_, err = cstore.SignEthereum("cold", "", crypto.EthereumMessageHash([]byte("msg")))
+		assert.Equal(t, keys.OfflineKeyError{Name: "cold"}, err)
+		err = cstore.Update("cold", "", "new")
+		assert.Equal(t, keys.OfflineKeyError{Name: "cold"}, err)
+		_, err = cstore.Upgrade("cold", "")
+		assert.Equal(t, keys.OfflineKeyError{Name: "cold"}, err)
+
+		// offline keys are exported and imported like any other
+		armor, err := cstore.Export("cold")
+		require.Nil(t, err, "%+v", err)
+		err = cstore.Import("cold2", armor)
+		require.Nil(t, err, "%+v", err)
+		info, err = cstore.Get("cold2")
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, keys.KeyOffline, info.Kind)
+
+		for _, name := range []string{"cold", "cold2"} {
+			err = cstore.Delete(name, "")
+			require.Nil(t, err, "%+v", err)
+		}
+		infos, err = cstore.List()
+		require.Nil(t, err, "%+v", err)
+		require.Equal(t, 1, len(infos))
+		assert.Equal(t, "hot", infos[0].Name)
+	}
+}
+
+func TestKeyKindText(t *testing.T) {
+	for _, kind := range []keys.KeyKind{keys.KeyLocal, keys.KeyOffline} {
+		text, err := kind.MarshalText()
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, kind.String(), string(text))
+		var parsed keys.KeyKind
+		require.Nil(t, parsed.UnmarshalText(text))
+		assert.Equal(t, kind, parsed)
+	}
+	_, err := keys.KeyKind(99).MarshalText()
+	assert.NotNil(t, err)
+	var parsed keys.KeyKind
+	assert.NotNil(t, parsed.UnmarshalText([]byte("hot")))
+}
+
+func TestSignerBackends(t *testing.T) {
+	ledger := keys.MockSigner{Seed: []byte("ledger")}
+	remote := keys.MockSigner{Seed: []byte("remote"), Algo: keys.AlgoEd25519}
+	db := dbm.NewMemDB()
+	cstore, err := keys.New(db, words.MustLoadCodec("english")).WithSigner(keys.KeyLedger, ledger)
+	require.Nil(t, err, "%+v", err)
+	cstore, err = cstore.WithSigner(keys.KeyRemote, remote)
+	require.Nil(t, err, "%+v", err)
+
+	path := keys.LedgerKey{Path: "44'/118'/0'/0/0"}
+	nano, err := cstore.CreateLedger("nano", path)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, keys.KeyLedger, nano.Kind)
+	assert.Equal(t, &path, nano.Ledger)
+	_, err = cstore.CreateLedger("nano", path)
+	assert.NotNil(t, err, "names are unique")
+
+	// the mock is deterministic, like a device
+	again, err := cstore.CreateLedger("again", path)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, nano.PubKey, again.PubKey)
+	other, err := cstore.CreateLedger("other", keys.LedgerKey{Path: "44'/118'/0'/0/1"})
+	require.Nil(t, err, "%+v", err)
+	assert.NotEqual(t, nano.PubKey, other.PubKey)
+
+	signer, err := cstore.CreateRemote("signer", keys.RemoteKey{Address: "tcp://10.0.0.1:26659", ID: "validator"})
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, keys.KeyRemote, signer.Kind)
+	_, ok := signer.PubKey.(crypto.PubKeyEd25519)
+	assert.True(t, ok, "%T", signer.PubKey)
+
+	// kinds survive storage
+	infos, err := cstore.List()
+	require.Nil(t, err, "%+v", err)
+	require.Equal(t, 4, len(infos))
+	info, err := cstore.Get("nano")
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, nano, info)
+	info, err = cstore.Get("signer")
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, signer, info)
+
+	// signing is dispatched by kind, and the passphrase is ignored
+	msg := []byte("welcome to cosmos")
+	for _, info := range []keys.Info{nano, signer} {
+		sig, pub, err := cstore.Sign(info.Name, "", msg)
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, info.PubKey, pub)
+		assert.True(t, pub.VerifyBytes(msg, sig))
+	}
+	_, err = cstore.SignEthereum("nano", "", crypto.EthereumMessageHash(msg))
+	assert.NotNil(t, err)
+	assert.NotNil(t, cstore.Update("nano", "", "new"))
+
+	// ledger keys are exported and imported like any other
+	armor, err := cstore.Export("nano")
+	require.Nil(t, err, "%+v", err)
+	require.Nil(t, cstore.Delete("nano", ""))
+	require.Nil(t, cstore.Import("nano", armor))
+	_, _, err = cstore.Sign("nano", "", msg)
+	require.Nil(t, err, "%+v", err)
+
+	// without a backend, or with the wrong device, signing fails
+	bare := keys.New(db, words.MustLoadCodec("english"))
+	_, _, err = bare.Sign("nano", "", msg)
+	assert.NotNil(t, err)
+	_, err = bare.CreateLedger("new", path)
+	assert.NotNil(t, err)
+	wrong, err := bare.WithSigner(keys.KeyLedger, keys.MockSigner{Seed: []byte("another ledger")})
+	require.Nil(t, err, "%+v", err)
+	_, _, err = wrong.Sign("nano", "", msg)
+	assert.NotNil(t, err)
+	unplugged, err := bare.WithSigner(keys.KeyLedger, keys.MockSigner{Err: fmt.Errorf("unplugged")})
+	require.Nil(t, err, "%+v", err)
+	_, _, err = unplugged.Sign("nano", "", msg)
+	assert.NotNil(t, err)
+	_, _, err = cstore.Sign("nano", "", msg)
+	assert.Nil(t, err, "WithSigner returns a copy")
+
+	// only ledger and remote keys have a backend
+	for _, kind := range []keys.KeyKind{keys.KeyLocal, keys.KeyOffline, keys.KeyKind(99)} {
+		assert.NotPanics(t, func() {
+			_, err = bare.WithSigner(kind, ledger)
+		})
+		assert.Equal(t, keys.UnsupportedKindError{Kind: kind}, err)
+	}
+	_, err = bare.WithSigner(keys.KeyLedger, nil)
+	assert.NotNil(t, err)
+}
+
+// externalPrivKey and externalPubKey stand in for a key type registered
+// by another package.
+type externalPrivKey struct{ crypto.PrivKeyEd25519 }
+type externalPubKey struct{ crypto.PubKeyEd25519 }
+
+var externalCdc = amino.NewCodec()
+
+func (k externalPrivKey) Bytes() []byte { return externalCdc.MustMarshalBinaryBare(k) }
+
+func (k *externalPrivKey) Zeroize() { crypto.Zeroize(k.PrivKeyEd25519[:]) }
+
+const algoExternal = keys.CryptoAlgo("test-external")
+
+func init() {
+	crypto.RegisterAmino(externalCdc)
+	crypto.RegisterKeyType(crypto.KeyType{
+		Name:     string(algoExternal),
+		SeedByte: 0x7e,
+		PubKey:   crypto.AminoType{Name: "test/PubKeyExternal", Value: externalPubKey{}},
+		PrivKey:  crypto.AminoType{Name: "test/PrivKeyExternal", Value: externalPrivKey{}},
+		GenPrivKeyFromSecret: func(secret []byte) crypto.PrivKey {
+			return externalPrivKey{crypto.GenPrivKeyEd25519FromSecret(secret)}
+		},
+	})
+}
+
+func TestExternalKeyType(t *testing.T) {
+	cstore := keys.NewWithKDF(dbm.NewMemDB(), words.MustLoadCodec("english"), keys.ScryptKDF{N: 1024, R: 8, P: 1})
+	info, seed, err := cstore.Create("ext", "1234", algoExternal)
+	require.Nil(t, err, "%+v", err)
+
+	msg := []byte("hello")
+	sig, pub, err := cstore.Sign("ext", "1234", msg)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, info.PubKey, pub)
+	assert.True(t, pub.VerifyBytes(msg, sig))
+	require.Nil(t, cstore.Update("ext", "1234", "5678"))
+
+	recovered, err := cstore.Recover("ext2", "1234", seed)
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, info.PubKey, recovered.PubKey)
+
+	assert.NotNil(t, cstore.Delete("ext", "1234"))
+	require.Nil(t, cstore.Delete("ext", "5678"))
+	require.Nil(t, cstore.Delete("ext2", "1234"))
+	infos, err := cstore.List()
+	require.Nil(t, err, "%+v", err)
+	assert.Empty(t, infos)
+}
@@ -1,32 +1,155 @@
 package keys
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"strings"
 
+	"github.com/pkg/errors"
+	crypto "github.com/tendermint/go-crypto"
+	"github.com/tendermint/go-crypto/keys/words"
+)
+
+// CryptoAlgo is the name of a key type registered with
+// crypto.RegisterKeyType.
 type CryptoAlgo string
 
 const (
 	AlgoEd25519   = CryptoAlgo("ed25519")
 	AlgoSecp256k1 = CryptoAlgo("secp256k1")
+	AlgoSchnorr   = CryptoAlgo("schnorr")
 )
 
 func cryptoAlgoToByte(key CryptoAlgo) byte {
-	switch key {
-	case AlgoEd25519:
-		return 0x01
-	case AlgoSecp256k1:
-		return 0x02
-	default:
+	kt, ok := crypto.KeyTypeByName(string(key))
+	if !ok || kt.SeedByte == 0 {
 		panic(fmt.Sprintf("Unexpected type key %v", key))
 	}
+	return kt.SeedByte
 }
 
 func byteToCryptoAlgo(b byte) CryptoAlgo {
-	switch b {
-	case 0x01:
-		return AlgoEd25519
-	case 0x02:
-		return AlgoSecp256k1
-	default:
+	kt, ok := crypto.KeyTypeBySeedByte(b)
+	if !ok {
 		panic(fmt.Sprintf("Unexpected type byte %X", b))
 	}
+	return CryptoAlgo(kt.Name)
+}
+
+//-------------------------------------
+// Helpers shared by the Keybase implementations.
+
+// newSeedKey generates a new key of the given algo, and returns it with
+// the seedphrase to recover it.
+func newSeedKey(codec words.Codec, algo CryptoAlgo) (crypto.PrivKey, string, error) {
+	// NOTE: secret is SHA256 hashed by secp256k1 and ed25519.
+	// 16 byte secret corresponds to 12 BIP39 words.
+	// XXX: Ledgers use 24 words now - should we ?
+	secret := crypto.CRandBytes(16)
+	defer crypto.Zeroize(secret)
+	priv, err := generate(algo, secret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// we append the type byte to the serialized secret to help with
+	// recovery
+	// ie [secret] = [type] + [secret]
+	typ := cryptoAlgoToByte(algo)
+	secret = append([]byte{typ}, secret...)
+	defer crypto.Zeroize(secret)
+
+	// return the mnemonic phrase
+	words, err := codec.BytesToWords(secret)
+	if err != nil {
+		return nil, "", err
+	}
+	return priv, strings.Join(words, " "), nil
+}
+
+// recoverSeedKey returns the key of a seedphrase from newSeedKey.
+func recoverSeedKey(codec words.Codec, seedphrase string) (crypto.PrivKey, error) {
+	words := strings.Split(strings.TrimSpace(seedphrase), " ")
+	secret, err := codec.WordsToBytes(words)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.Zeroize(secret)
+
+	// secret is comprised of the actual secret with the type
+	// appended.
+	// ie [secret] = [type] + [secret]
+	typ, secret := secret[0], secret[1:]
+	algo := byteToCryptoAlgo(typ)
+	return generate(algo, secret)
+}
+
+func generate(algo CryptoAlgo, secret []byte) (crypto.PrivKey, error) {
+	kt, ok := crypto.KeyTypeByName(string(algo))
+	if !ok || kt.SeedByte == 0 {
+		err := errors.Errorf("Cannot generate keys for algorithm: %s", algo)
+		return nil, err
+	}
+	return kt.GenPrivKeyFromSecret(secret), nil
+}
+
+// getByAddress returns the key in kb with the given address.
+func getByAddress(kb Keybase, address crypto.Address) (Info, error) {
+	infos, err := kb.List()
+	if err != nil {
+		return Info{}, err
+	}
+	for _, info := range infos {
+		if bytes.Equal(info.Address(), address) {
+			return info, nil
+		}
+	}
+	return Info{}, errors.Errorf("No key with address %X", []byte(address))
+}
+
+// upgradeAll upgrades the keys in kb below its policy which have a
+// passphrase in passphrases, by name. It returns the names of the
+// upgraded keys, and stops at the first error. Key files which List
+// skips are skipped too, and their KeyFileErrors is returned at the
+// end.
+func upgradeAll(kb Keybase, passphrases map[string]string) ([]string, error) {
+	infos, listErr := kb.List()
+	if _, ok := listErr.(KeyFileErrors); listErr != nil && !ok {
+		return nil, listErr
+	}
+	var upgraded []string
+	for _, info := range infos {
+		passphrase, ok := passphrases[info.Name]
+		if !ok || !info.WeakKDF {
+			continue
+		}
+		ok, err := kb.Upgrade(info.Name, passphrase)
+		if err != nil {
+			return upgraded, errors.Wrapf(err, "Upgrading key %s", info.Name)
+		}
+		if ok {
+			upgraded = append(upgraded, info.Name)
+		}
+	}
+	return upgraded, listErr
+}
+
+// signWith signs msg with signer and returns the signature together
+// with the signer's public key.
+func signWith(signer crypto.Signer, msg []byte) (crypto.Signature, crypto.PubKey, error) {
+	sig, err := signer.Sign(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sig, signer.PubKey(), nil
+}
+
+// signEthereumWith signs an Ethereum digest with the named key, which
+// must be a secp256k1 key.
+func signEthereumWith(name string, priv crypto.PrivKey, hash []byte) (crypto.SignatureEthereum, error) {
+	privSecp, ok := priv.(*crypto.PrivKeySecp256k1)
+	if !ok {
+		return crypto.SignatureEthereum{}, errors.Errorf("Key %s is not a secp256k1 key", name)
+	}
+	return privSecp.SignEthereumHash(hash)
 }
@@ -4,10 +4,7 @@ import (
 	"encoding/hex"
 	"fmt"
 
-	cmn "github.com/tendermint/tmlibs/common"
-
 	"github.com/tendermint/go-crypto"
-	"github.com/tendermint/go-crypto/keys/bcrypt"
 )
 
 const (
@@ -40,60 +37,85 @@ func unarmorInfoBytes(armorStr string) (bz []byte, err error) {
 	return
 }
 
-func encryptArmorPrivKey(privKey crypto.PrivKey, passphrase string) string {
-	saltBytes, encBytes := encryptPrivKey(privKey, passphrase)
-	header := map[string]string{
-		"kdf":  "bcrypt",
-		"salt": fmt.Sprintf("%X", saltBytes),
+func encryptArmorPrivKey(privKey crypto.PrivKey, passphrase string, kdf KDF) (string, error) {
+	header := kdf.Params()
+	header["kdf"] = kdf.Name()
+	// don't write a key which unarmorDecryptPrivKey can't read back,
+	// e.g. with an unregistered KDF
+	if _, err := kdfFromHeader(header); err != nil {
+		return "", err
+	}
+	saltBytes, encBytes, err := encryptPrivKey(privKey, passphrase, kdf)
+	if err != nil {
+		return "", err
 	}
+	header["salt"] = fmt.Sprintf("%X", saltBytes)
 	armorStr := crypto.EncodeArmor(blockTypePrivKey, header, encBytes)
-	return armorStr
+	return armorStr, nil
 }
 
-func unarmorDecryptPrivKey(armorStr string, passphrase string) (crypto.PrivKey, error) {
-	var privKey crypto.PrivKey
+// unarmorDecryptPrivKey decrypts the key in armorStr, with the KDF
+// recorded in its headers. The caller must Zeroize the key after use.
+func unarmorDecryptPrivKey(armorStr string, passphrase string) (crypto.ZeroizablePrivKey, error) {
 	blockType, header, encBytes, err := crypto.DecodeArmor(armorStr)
 	if err != nil {
-		return privKey, err
+		return nil, err
 	}
 	if blockType != blockTypePrivKey {
-		return privKey, fmt.Errorf("Unrecognized armor type: %v", blockType)
+		return nil, fmt.Errorf("Unrecognized armor type: %v", blockType)
 	}
-	if header["kdf"] != "bcrypt" {
-		return privKey, fmt.Errorf("Unrecognized KDF type: %v", header["KDF"])
+	kdf, err := kdfFromHeader(header)
+	if err != nil {
+		return nil, err
 	}
 	if header["salt"] == "" {
-		return privKey, fmt.Errorf("Missing salt bytes")
+		return nil, fmt.Errorf("Missing salt bytes")
 	}
 	saltBytes, err := hex.DecodeString(header["salt"])
 	if err != nil {
-		return privKey, fmt.Errorf("Error decoding salt: %v", err.Error())
+		return nil, fmt.Errorf("Error decoding salt: %v", err.Error())
 	}
-	privKey, err = decryptPrivKey(saltBytes, encBytes, passphrase)
-	return privKey, err
+	return decryptPrivKey(saltBytes, encBytes, passphrase, kdf)
 }
 
-func encryptPrivKey(privKey crypto.PrivKey, passphrase string) (saltBytes []byte, encBytes []byte) {
-	saltBytes = crypto.CRandBytes(16)
-	key, err := bcrypt.GenerateFromPassword(saltBytes, []byte(passphrase), 12) // TODO parameterize.  12 is good today (2016)
+func encryptPrivKey(privKey crypto.PrivKey, passphrase string, kdf KDF) (saltBytes []byte, encBytes []byte, err error) {
+	saltBytes = crypto.CRandBytes(kdfSaltSize)
+	key, err := passphraseKey(kdf, saltBytes, passphrase)
 	if err != nil {
-		cmn.Exit("Error generating bcrypt key from passphrase: " + err.Error())
+		return nil, nil, err
 	}
-	key = crypto.Sha256(key) // Get 32 bytes
+	defer key.Destroy()
 	privKeyBytes := privKey.Bytes()
-	return saltBytes, crypto.EncryptSymmetric(privKeyBytes, key)
+	defer crypto.Zeroize(privKeyBytes)
+	return saltBytes, crypto.EncryptSymmetric(privKeyBytes, key.Bytes()), nil
 }
 
-func decryptPrivKey(saltBytes []byte, encBytes []byte, passphrase string) (privKey crypto.PrivKey, err error) {
-	key, err := bcrypt.GenerateFromPassword(saltBytes, []byte(passphrase), 12) // TODO parameterize.  12 is good today (2016)
+func decryptPrivKey(saltBytes []byte, encBytes []byte, passphrase string, kdf KDF) (crypto.ZeroizablePrivKey, error) {
+	key, err := passphraseKey(kdf, saltBytes, passphrase)
 	if err != nil {
-		cmn.Exit("Error generating bcrypt key from passphrase: " + err.Error())
+		return nil, err
 	}
-	key = crypto.Sha256(key) // Get 32 bytes
-	privKeyBytes, err := crypto.DecryptSymmetric(encBytes, key)
+	defer key.Destroy()
+	privKeyBytes, err := crypto.DecryptSymmetric(encBytes, key.Bytes())
 	if err != nil {
-		return privKey, err
+		return nil, err
+	}
+	defer crypto.Zeroize(privKeyBytes)
+	return crypto.PrivKeyFromBytesZeroizable(privKeyBytes)
+}
+
+// passphraseKey derives the 32 byte encryption key from the passphrase.
+// Intermediate secrets are wiped.
+func passphraseKey(kdf KDF, saltBytes []byte, passphrase string) (*crypto.SecretBytes, error) {
+	passBytes := []byte(passphrase)
+	defer crypto.Zeroize(passBytes)
+	key, err := kdf.DeriveKey(passBytes, saltBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Error deriving key from passphrase: %v", err)
+	}
+	if len(key) != kdfKeySize {
+		crypto.Zeroize(key)
+		return nil, fmt.Errorf("%s derived a %d byte key", kdf.Name(), len(key))
 	}
-	privKey, err = crypto.PrivKeyFromBytes(privKeyBytes)
-	return privKey, err
+	return crypto.SecretBytesFrom(key), nil
 }
@@ -0,0 +1,61 @@
+package keys
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	crypto "github.com/tendermint/go-crypto"
+)
+
+// MockSigner is a deterministic SignerBackend, to test ledger and remote
+// keys without devices. The key at each location, i.e. Ledger path or
+// remote address and ID, is derived from Seed, so a location always has
+// the same key, like on a device.
+type MockSigner struct {
+	Seed []byte
+	// Algo is the type of the keys, AlgoSecp256k1 by default.
+	Algo CryptoAlgo
+	// Err, if set, is returned by PubKey and Sign, e.g. to simulate an
+	// unplugged device.
+	Err error
+}
+
+var _ SignerBackend = MockSigner{}
+
+func (m MockSigner) PubKey(info Info) (crypto.PubKey, error) {
+	priv, err := m.privKey(info)
+	if err != nil {
+		return nil, err
+	}
+	return priv.PubKey(), nil
+}
+
+func (m MockSigner) Sign(info Info, msg []byte) (crypto.Signature, error) {
+	priv, err := m.privKey(info)
+	if err != nil {
+		return nil, err
+	}
+	return priv.Sign(msg)
+}
+
+// privKey derives the key at the location of info.
+func (m MockSigner) privKey(info Info) (crypto.PrivKey, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var location string
+	switch {
+	case info.Kind == KeyLedger && info.Ledger != nil:
+		location = fmt.Sprintf("ledger:%s", info.Ledger.Path)
+	case info.Kind == KeyRemote && info.Remote != nil:
+		location = fmt.Sprintf("remote:%s/%s", info.Remote.Address, info.Remote.ID)
+	default:
+		return nil, errors.Errorf("MockSigner can't locate %v key %s", info.Kind, info.Name)
+	}
+	algo := m.Algo
+	if algo == "" {
+		algo = AlgoSecp256k1
+	}
+	secret := crypto.Sha256(append(append([]byte{}, m.Seed...), location...))
+	return generate(algo, secret)
+}
@@ -0,0 +1,68 @@
+package keys
+
+import (
+	"github.com/tendermint/go-crypto"
+)
+
+// KDFPolicy decides which KDFs are strong enough for stored keys.
+// Keys encrypted with a KDF below the policy are flagged by List and
+// Get, and re-encrypted with Current when they're next unlocked, see
+// dbKeybase.Sign and dbKeybase.Upgrade.
+//
+// A KDF meets the policy if it is Current, or if all its parameters
+// are at least those of the minimum for its type. A zero minimum
+// doesn't accept any parameters of that type.
+type KDFPolicy struct {
+	// Current encrypts new, updated and upgraded keys.
+	Current KDF
+
+	MinArgon2id         Argon2idKDF
+	MinScrypt           ScryptKDF
+	MinBcryptCost       int
+	MinPBKDF2Iterations int
+}
+
+// DefaultKDFPolicy encrypts keys with DefaultKDF, and accepts Argon2id
+// with the same memory and iterations, and scrypt with the parameters
+// recommended for interactive logins in 2017, N = 2^15, r = 8, p = 1.
+// bcrypt and pbkdf2 keys are always upgraded.
+var DefaultKDFPolicy = KDFPolicy{
+	Current:     DefaultKDF,
+	MinArgon2id: Argon2idKDF{Memory: 64 * 1024, Iterations: 3, Parallelism: 1},
+	MinScrypt:   ScryptKDF{N: 1 << 15, R: 8, P: 1},
+}
+
+// Accepts returns true iff kdf meets the policy.
+func (p KDFPolicy) Accepts(kdf KDF) bool {
+	if kdf == p.Current {
+		return true
+	}
+	switch kdf := kdf.(type) {
+	case Argon2idKDF:
+		min := p.MinArgon2id
+		return min != Argon2idKDF{} &&
+			kdf.Memory >= min.Memory && kdf.Iterations >= min.Iterations &&
+			kdf.Parallelism >= min.Parallelism
+	case ScryptKDF:
+		min := p.MinScrypt
+		return min != ScryptKDF{} &&
+			kdf.N >= min.N && kdf.R >= min.R && kdf.P >= min.P
+	case BcryptKDF:
+		return p.MinBcryptCost > 0 && kdf.Cost >= p.MinBcryptCost
+	case PBKDF2KDF:
+		return p.MinPBKDF2Iterations > 0 && kdf.Iterations >= p.MinPBKDF2Iterations
+	default:
+		return false
+	}
+}
+
+// acceptsArmor returns true iff the KDF of the armored key meets the
+// policy.
+func (p KDFPolicy) acceptsArmor(armorStr string) bool {
+	_, header, _, err := crypto.DecodeArmor(armorStr)
+	if err != nil {
+		return false
+	}
+	kdf, err := kdfFromHeader(header)
+	return err == nil && p.Accepts(kdf)
+}
@@ -0,0 +1,52 @@
+package keys
+
+import (
+	"github.com/pkg/errors"
+	crypto "github.com/tendermint/go-crypto"
+)
+
+// SignerBackend signs with keys whose private key isn't in the keybase,
+// like keys on a Ledger or held by a remote signer. dbKeybase.Sign
+// dispatches to the backend for the kind of key, see
+// dbKeybase.WithSigner.
+type SignerBackend interface {
+	// PubKey returns the public key of the key located by info, when
+	// the key is created. info.PubKey isn't set yet.
+	PubKey(info Info) (crypto.PubKey, error)
+	// Sign signs msg with the key of info.
+	Sign(info Info, msg []byte) (crypto.Signature, error)
+}
+
+// signWithBackend signs msg with the ledger or remote key of info. The
+// signature must be valid for info.PubKey, so that a backend can't
+// sign with another key, e.g. if another device is plugged in.
+func signWithBackend(signers map[KeyKind]SignerBackend, info Info, msg []byte) (crypto.Signature, crypto.PubKey, error) {
+	backend, err := signerFor(signers, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, err := backend.Sign(info, msg)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Signing with %v key %s", info.Kind, info.Name)
+	}
+	if !info.PubKey.VerifyBytes(msg, sig) {
+		return nil, nil, errors.Errorf("The %v signer signed with another key than %s", info.Kind, info.Name)
+	}
+	return sig, info.PubKey, nil
+}
+
+// signerFor returns the backend for the kind of info.
+func signerFor(signers map[KeyKind]SignerBackend, info Info) (SignerBackend, error) {
+	switch info.Kind {
+	case KeyOffline:
+		return nil, OfflineKeyError{Name: info.Name}
+	case KeyLedger, KeyRemote:
+		backend, ok := signers[info.Kind]
+		if !ok {
+			return nil, errors.Errorf("No signer for %v key %s", info.Kind, info.Name)
+		}
+		return backend, nil
+	default:
+		return nil, errors.Errorf("Key %s is a %v key, which has no signer", info.Name, info.Kind)
+	}
+}
@@ -1,6 +1,8 @@
 package keys
 
 import (
+	"fmt"
+
 	crypto "github.com/tendermint/go-crypto"
 )
 
@@ -8,24 +10,62 @@ import (
 type Keybase interface {
 	// Sign some bytes
 	Sign(name, passphrase string, msg []byte) (crypto.Signature, crypto.PubKey, error)
+	// SignEthereum signs a digest from crypto.EthereumMessageHash or
+	// crypto.TypedData.Hash with a secp256k1 key
+	SignEthereum(name, passphrase string, hash []byte) (crypto.SignatureEthereum, error)
 	// Create a new keypair
 	Create(name, passphrase string, algo CryptoAlgo) (info Info, seed string, err error)
 	// Recover takes a seedphrase and loads in the key
 	Recover(name, passphrase, seedphrase string) (info Info, erro error)
+	// CreateOffline stores a watch-only key, for which we only know the
+	// public key. Signing with it returns an OfflineKeyError.
+	CreateOffline(name string, pub crypto.PubKey) (info Info, err error)
 	List() ([]Info, error)
 	Get(name string) (Info, error)
+	// GetByAddress finds a key by its address. Use
+	// crypto.AddressFromBech32 to look up a bech32 address.
+	GetByAddress(address crypto.Address) (Info, error)
 	Update(name, oldpass, newpass string) error
+	// Upgrade re-encrypts a key whose KDF is below the keybase's
+	// policy, see Info.WeakKDF
+	Upgrade(name, passphrase string) (upgraded bool, err error)
+	UpgradeAll(passphrases map[string]string) (upgraded []string, err error)
 	Delete(name, passphrase string) error
 
 	Import(name string, armor string) (err error)
 	Export(name string) (armor string, err error)
 }
 
-// Info is the public information about a key
+// Info is the public information about a key.
+//
+// It's a union tagged by Kind: PrivKeyArmor and WeakKDF are only set
+// for local keys, Ledger for ledger keys and Remote for remote keys.
+// Offline keys only have a public key. Local keys from other tools in
+// a file keybase, like geth, have no PubKey until they are unlocked.
 type Info struct {
 	Name         string        `json:"name"`
 	PubKey       crypto.PubKey `json:"pubkey"`
-	PrivKeyArmor string        `json:"privkey.armor"`
+	PrivKeyArmor string        `json:"privkey.armor,omitempty"`
+	// WeakKDF is set by the keybase if the key is encrypted with a KDF
+	// below its policy. It's never stored.
+	WeakKDF bool       `json:"weak_kdf,omitempty"`
+	Kind    KeyKind    `json:"kind"`
+	Ledger  *LedgerKey `json:"ledger,omitempty"`
+	Remote  *RemoteKey `json:"remote,omitempty"`
+}
+
+// LedgerKey locates a key on a Ledger device.
+type LedgerKey struct {
+	// Path is the BIP32 derivation path, e.g. "44'/118'/0'/0/0".
+	Path string `json:"path"`
+}
+
+// RemoteKey locates a key held by a remote signer.
+type RemoteKey struct {
+	// Address of the signer, e.g. "tcp://10.0.0.1:26659".
+	Address string `json:"address"`
+	// ID of the key on the signer.
+	ID string `json:"id"`
 }
 
 func newInfo(name string, pub crypto.PubKey, privArmor string) Info {
@@ -36,11 +76,55 @@ func newInfo(name string, pub crypto.PubKey, privArmor string) Info {
 	}
 }
 
-// Address is a helper function to calculate the address from the pubkey
+func newOfflineInfo(name string, pub crypto.PubKey) Info {
+	return Info{
+		Name:   name,
+		PubKey: pub,
+		Kind:   KeyOffline,
+	}
+}
+
+// validate returns an error unless exactly the fields of the kind of
+// key are set.
+func (i Info) validate() error {
+	if i.PubKey == nil {
+		return fmt.Errorf("Key %s has no public key", i.Name)
+	}
+	ok := false
+	switch i.Kind {
+	case KeyLocal:
+		ok = i.PrivKeyArmor != "" && i.Ledger == nil && i.Remote == nil
+	case KeyOffline:
+		ok = i.PrivKeyArmor == "" && i.Ledger == nil && i.Remote == nil
+	case KeyLedger:
+		ok = i.PrivKeyArmor == "" && i.Ledger != nil && i.Remote == nil
+	case KeyRemote:
+		ok = i.PrivKeyArmor == "" && i.Ledger == nil && i.Remote != nil
+	}
+	if !ok {
+		return fmt.Errorf("Key %s has invalid fields for a %v key", i.Name, i.Kind)
+	}
+	return nil
+}
+
+// Address is a helper function to calculate the address from the pubkey.
+// It's nil if there is no pubkey.
 func (i Info) Address() []byte {
+	if i.PubKey == nil {
+		return nil
+	}
 	return i.PubKey.Address()
 }
 
+// Bech32Address returns the address in Bech32, with the given human
+// readable prefix
+func (i Info) Bech32Address(hrp string) (string, error) {
+	if i.PubKey == nil {
+		return "", fmt.Errorf("Key %s has no public key", i.Name)
+	}
+	return crypto.AddressToBech32(hrp, i.PubKey.Address())
+}
+
 func (i Info) bytes() []byte {
 	bz, err := cdc.MarshalBinaryBare(i)
 	if err != nil {
@@ -53,3 +137,71 @@ func readInfo(bz []byte) (info Info, err error) {
 	err = cdc.UnmarshalBinaryBare(bz, &info)
 	return
 }
+
+// KeyKind tells where the private key of an Info is. Signing with
+// ledger and remote keys is dispatched to a SignerBackend.
+type KeyKind byte
+
+const (
+	// KeyLocal keys are encrypted in the keybase. It's the zero value,
+	// so it's the kind of keys stored before kinds.
+	KeyLocal KeyKind = iota
+	// KeyOffline keys are watch-only: the keybase has no private key.
+	KeyOffline
+	// KeyLedger keys are on a Ledger device.
+	KeyLedger
+	// KeyRemote keys are held by a remote signer.
+	KeyRemote
+)
+
+var keyKindNames = map[KeyKind]string{
+	KeyLocal:   "local",
+	KeyOffline: "offline",
+	KeyLedger:  "ledger",
+	KeyRemote:  "remote",
+}
+
+func (k KeyKind) String() string {
+	if name, ok := keyKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("KeyKind(%d)", byte(k))
+}
+
+func (k KeyKind) MarshalText() ([]byte, error) {
+	if _, ok := keyKindNames[k]; !ok {
+		return nil, fmt.Errorf("Unknown key kind %d", byte(k))
+	}
+	return []byte(k.String()), nil
+}
+
+func (k *KeyKind) UnmarshalText(text []byte) error {
+	for kind, name := range keyKindNames {
+		if name == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown key kind %q", text)
+}
+
+// OfflineKeyError is returned when an operation needs the private key
+// of an offline key.
+type OfflineKeyError struct {
+	Name string
+}
+
+func (e OfflineKeyError) Error() string {
+	return fmt.Sprintf("Key %s is offline: there is no private key", e.Name)
+}
+
+// UnsupportedKindError is returned when a keybase can't store or sign
+// with keys of a kind, e.g. the file keybase with ledger and remote
+// keys.
+type UnsupportedKindError struct {
+	Kind KeyKind
+}
+
+func (e UnsupportedKindError) Error() string {
+	return fmt.Sprintf("The keybase doesn't support %v keys", e.Kind)
+}
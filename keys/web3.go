@@ -0,0 +1,337 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tendermint/go-crypto"
+)
+
+// Key files in the Web3 Secret Storage format, version 3, see
+// https://github.com/ethereum/wiki/wiki/Web3-Secret-Storage-Definition
+//
+// The derived key is 32 bytes: the first half is the AES-128-CTR key,
+// the second half authenticates the ciphertext in the MAC,
+// Keccak256(key[16:32] || ciphertext).
+//
+// secp256k1 keys are encrypted as their 32 raw bytes, as Ethereum
+// tools expect. Other keys are encrypted in their amino encoding.
+//
+// Offline keys have neither crypto nor address, so that geth skips
+// them.
+
+const (
+	web3Version   = 3
+	web3Cipher    = "aes-128-ctr"
+	web3PRF       = "hmac-sha256"
+	web3SaltSize  = 32
+	web3MaxKeyLen = 1 << 16
+)
+
+// web3Key is a key file. Meta is our extension, which other tools
+// ignore.
+type web3Key struct {
+	Address string      `json:"address,omitempty"`
+	Crypto  *web3Crypto `json:"crypto,omitempty"`
+	ID      string      `json:"id"`
+	Version int         `json:"version"`
+	Meta    *web3Meta   `json:"tendermint,omitempty"`
+}
+
+type web3Crypto struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams web3CipherParams       `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    map[string]interface{} `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+type web3CipherParams struct {
+	IV string `json:"iv"`
+}
+
+// web3Meta holds the public key, so that keys can be listed without
+// their passphrase, and the kind of key.
+type web3Meta struct {
+	// PubKey is the hex of the amino encoding.
+	PubKey string  `json:"pubkey"`
+	Kind   KeyKind `json:"kind,omitempty"`
+}
+
+// encryptWeb3Key returns the key file of priv, encrypted with kdf,
+// which must be scrypt or pbkdf2.
+func encryptWeb3Key(priv crypto.PrivKey, passphrase string, kdf KDF) ([]byte, error) {
+	kdfParams := map[string]interface{}{}
+	switch kdf.(type) {
+	case ScryptKDF:
+	case PBKDF2KDF:
+		kdfParams["prf"] = web3PRF
+	default:
+		return nil, fmt.Errorf("Web3 Secret Storage doesn't support the %s KDF", kdf.Name())
+	}
+	for name, value := range kdf.Params() {
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid KDF parameter %q: %v", name, err)
+		}
+		kdfParams[name] = n
+	}
+	salt := crypto.CRandBytes(web3SaltSize)
+	kdfParams["salt"] = hex.EncodeToString(salt)
+	kdfParams["dklen"] = kdfKeySize
+
+	key, err := passphraseKey(kdf, salt, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Destroy()
+
+	plaintext := web3PrivKeyBytes(priv)
+	defer crypto.Zeroize(plaintext)
+	iv := crypto.CRandBytes(aes.BlockSize)
+	ciphertext, err := aesCTR(key.Bytes()[:16], iv, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	pub := priv.PubKey()
+	return json.Marshal(web3Key{
+		Address: web3Address(pub),
+		Crypto: &web3Crypto{
+			Cipher:       web3Cipher,
+			CipherText:   hex.EncodeToString(ciphertext),
+			CipherParams: web3CipherParams{IV: hex.EncodeToString(iv)},
+			KDF:          kdf.Name(),
+			KDFParams:    kdfParams,
+			MAC:          hex.EncodeToString(web3MAC(key.Bytes(), ciphertext)),
+		},
+		ID:      newUUID(),
+		Version: web3Version,
+		Meta:    &web3Meta{PubKey: hex.EncodeToString(pub.Bytes())},
+	})
+}
+
+// offlineWeb3Key returns the key file of an offline key.
+func offlineWeb3Key(pub crypto.PubKey) ([]byte, error) {
+	return json.Marshal(web3Key{
+		ID:      newUUID(),
+		Version: web3Version,
+		Meta:    &web3Meta{PubKey: hex.EncodeToString(pub.Bytes()), Kind: KeyOffline},
+	})
+}
+
+// parseWeb3Key parses a key file. Numbers are kept as json.Number, for
+// kdf.
+func parseWeb3Key(bz []byte) (web3Key, error) {
+	var key web3Key
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+	if err := dec.Decode(&key); err != nil {
+		return key, fmt.Errorf("Invalid key file: %v", err)
+	}
+	if key.Version != web3Version {
+		return key, fmt.Errorf("Unrecognized key file version: %v", key.Version)
+	}
+	return key, nil
+}
+
+// kind returns the kind of key in the file.
+func (key web3Key) kind() KeyKind {
+	if key.Meta == nil {
+		return KeyLocal
+	}
+	return key.Meta.Kind
+}
+
+// pubKey returns the public key from Meta, or nil if there is none.
+func (key web3Key) pubKey() (crypto.PubKey, error) {
+	if key.Meta == nil {
+		return nil, nil
+	}
+	bz, err := hex.DecodeString(key.Meta.PubKey)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding public key: %v", err)
+	}
+	return crypto.PubKeyFromBytes(bz)
+}
+
+// address returns the address of the key file in lower case, without
+// 0x.
+func (key web3Key) address() string {
+	return strings.ToLower(strings.TrimPrefix(key.Address, "0x"))
+}
+
+// withPubKey returns the key file with pub added to Meta.
+func (key web3Key) withPubKey(pub crypto.PubKey) ([]byte, error) {
+	key.Meta = &web3Meta{PubKey: hex.EncodeToString(pub.Bytes()), Kind: key.kind()}
+	return json.Marshal(key)
+}
+
+// kdf returns the KDF and salt of the key file. The parameters are
+// checked like those in armor headers.
+func (key web3Key) kdf() (KDF, []byte, error) {
+	if key.Crypto == nil {
+		return nil, nil, fmt.Errorf("Key file has no private key")
+	}
+	header := map[string]string{"kdf": key.Crypto.KDF}
+	var salt []byte
+	for name, value := range key.Crypto.KDFParams {
+		switch name {
+		case "salt":
+			s, _ := value.(string)
+			var err error
+			if salt, err = hex.DecodeString(s); err != nil || len(salt) == 0 {
+				return nil, nil, fmt.Errorf("Invalid salt %q", s)
+			}
+		case "dklen":
+			if fmt.Sprint(value) != strconv.Itoa(kdfKeySize) {
+				return nil, nil, fmt.Errorf("Unsupported dklen: %v", value)
+			}
+		case "prf":
+			if value != web3PRF {
+				return nil, nil, fmt.Errorf("Unsupported prf: %v", value)
+			}
+		default:
+			header[name] = fmt.Sprint(value)
+		}
+	}
+	if salt == nil {
+		return nil, nil, fmt.Errorf("Missing salt bytes")
+	}
+	if _, ok := key.Crypto.KDFParams["dklen"]; !ok {
+		return nil, nil, fmt.Errorf("Missing dklen")
+	}
+	kdf, err := kdfFromHeader(header)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch kdf.(type) {
+	case ScryptKDF, PBKDF2KDF:
+		return kdf, salt, nil
+	default:
+		return nil, nil, fmt.Errorf("Unrecognized KDF type: %v", key.Crypto.KDF)
+	}
+}
+
+// decrypt returns the private key of the key file. If the file has a
+// public key, it must match, else the address must, if there is one.
+// The caller must Zeroize the key after use.
+func (key web3Key) decrypt(passphrase string) (crypto.ZeroizablePrivKey, error) {
+	if key.Crypto == nil {
+		return nil, fmt.Errorf("Key file has no private key")
+	}
+	if key.Crypto.Cipher != web3Cipher {
+		return nil, fmt.Errorf("Unrecognized cipher: %v", key.Crypto.Cipher)
+	}
+	kdf, salt, err := key.kdf()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := key.pubKey()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := hex.DecodeString(key.Crypto.CipherText)
+	if err != nil || len(ciphertext) > web3MaxKeyLen {
+		return nil, fmt.Errorf("Invalid ciphertext")
+	}
+	iv, err := hex.DecodeString(key.Crypto.CipherParams.IV)
+	if err != nil || len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("Invalid iv")
+	}
+	mac, err := hex.DecodeString(key.Crypto.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid mac")
+	}
+
+	dk, err := passphraseKey(kdf, salt, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer dk.Destroy()
+	if !hmac.Equal(web3MAC(dk.Bytes(), ciphertext), mac) {
+		return nil, fmt.Errorf("Ciphertext decryption failed")
+	}
+	plaintext, err := aesCTR(dk.Bytes()[:16], iv, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.Zeroize(plaintext)
+
+	var priv crypto.ZeroizablePrivKey
+	if _, ok := pub.(crypto.PubKeySecp256k1); ok || pub == nil {
+		if len(plaintext) != len(crypto.PrivKeySecp256k1{}) {
+			return nil, fmt.Errorf("Invalid secp256k1 key length: %d", len(plaintext))
+		}
+		privSecp := new(crypto.PrivKeySecp256k1)
+		copy(privSecp[:], plaintext)
+		priv = privSecp
+	} else if priv, err = crypto.PrivKeyFromBytesZeroizable(plaintext); err != nil {
+		return nil, err
+	}
+	if pub != nil && !priv.PubKey().Equals(pub) {
+		priv.Zeroize()
+		return nil, fmt.Errorf("Key file public key doesn't match the private key")
+	}
+	if pub == nil && key.address() != "" && key.address() != web3Address(priv.PubKey()) {
+		priv.Zeroize()
+		return nil, fmt.Errorf("Key file address doesn't match the private key")
+	}
+	return priv, nil
+}
+
+// web3PrivKeyBytes returns the plaintext of priv.
+func web3PrivKeyBytes(priv crypto.PrivKey) []byte {
+	switch priv := priv.(type) {
+	case crypto.PrivKeySecp256k1:
+		return append([]byte{}, priv[:]...)
+	case *crypto.PrivKeySecp256k1:
+		return append([]byte{}, priv[:]...)
+	default:
+		return priv.Bytes()
+	}
+}
+
+// web3Address returns the Ethereum address of a secp256k1 key, and the
+// address of other keys, in lower case hex without 0x, like geth.
+func web3Address(pub crypto.PubKey) string {
+	if pubSecp, ok := pub.(crypto.PubKeySecp256k1); ok {
+		if addr, err := pubSecp.EthereumAddress(); err == nil {
+			return hex.EncodeToString(addr[:])
+		}
+	}
+	return hex.EncodeToString(pub.Address())
+}
+
+func web3MAC(derivedKey, ciphertext []byte) []byte {
+	h := crypto.HashKeccak256.New()
+	h.Write(derivedKey[16:32])
+	h.Write(ciphertext)
+	return h.Sum(nil)
+}
+
+func aesCTR(key, iv, in []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, iv).XORKeyStream(out, in)
+	return out, nil
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	u := crypto.CRandBytes(16)
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
@@ -0,0 +1,139 @@
+package keys
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	crypto "github.com/tendermint/go-crypto"
+)
+
+// The test vectors of the Web3 Secret Storage definition, with the
+// password "testpassword".
+const (
+	web3VectorPBKDF2 = `{
+  "crypto": {
+    "cipher": "aes-128-ctr",
+    "cipherparams": {"iv": "6087dab2f9fdbbfaddc31a909735c1e6"},
+    "ciphertext": "5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46",
+    "kdf": "pbkdf2",
+    "kdfparams": {
+      "c": 262144,
+      "dklen": 32,
+      "prf": "hmac-sha256",
+      "salt": "ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"
+    },
+    "mac": "517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"
+  },
+  "id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+  "version": 3
+}`
+	web3VectorScrypt = `{
+  "crypto": {
+    "cipher": "aes-128-ctr",
+    "cipherparams": {"iv": "83dbcc02d8ccb40e466191a123791e0e"},
+    "ciphertext": "d172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c",
+    "kdf": "scrypt",
+    "kdfparams": {
+      "dklen": 32,
+      "n": 262144,
+      "p": 8,
+      "r": 1,
+      "salt": "ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19"
+    },
+    "mac": "2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097"
+  },
+  "id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+  "version": 3
+}`
+	web3VectorPrivKey = "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d"
+)
+
+func TestWeb3Vectors(t *testing.T) {
+	for _, vector := range []string{web3VectorPBKDF2, web3VectorScrypt} {
+		key, err := parseWeb3Key([]byte(vector))
+		require.Nil(t, err, "%+v", err)
+		pub, err := key.pubKey()
+		require.Nil(t, err, "%+v", err)
+		assert.Nil(t, pub)
+
+		priv, err := key.decrypt("testpassword")
+		require.Nil(t, err, "%s: %+v", key.Crypto.KDF, err)
+		privSecp, ok := priv.(*crypto.PrivKeySecp256k1)
+		require.True(t, ok, "%T", priv)
+		assert.Equal(t, web3VectorPrivKey, hex.EncodeToString(privSecp[:]))
+		priv.Zeroize()
+
+		_, err = key.decrypt("wrong")
+		assert.NotNil(t, err)
+	}
+}
+
+func TestWeb3KeyRoundTrip(t *testing.T) {
+	secp := crypto.GenPrivKeySecp256k1()
+	for _, privKey := range []crypto.PrivKey{secp, crypto.GenPrivKeyEd25519()} {
+		for _, kdf := range []KDF{ScryptKDF{N: 1024, R: 8, P: 1}, PBKDF2KDF{Iterations: 1000}} {
+			bz, err := encryptWeb3Key(privKey, "passphrase", kdf)
+			require.Nil(t, err, "%+v", err)
+			key, err := parseWeb3Key(bz)
+			require.Nil(t, err, "%+v", err)
+			assert.Equal(t, kdf.Name(), key.Crypto.KDF)
+			parsed, _, err := key.kdf()
+			require.Nil(t, err, "%+v", err)
+			assert.Equal(t, kdf, parsed)
+			pub, err := key.pubKey()
+			require.Nil(t, err, "%+v", err)
+			assert.Equal(t, privKey.PubKey(), pub)
+
+			priv, err := key.decrypt("passphrase")
+			require.Nil(t, err, "%+v", err)
+			assert.Equal(t, privKey.Bytes(), priv.Bytes())
+			_, err = key.decrypt("wrong")
+			assert.NotNil(t, err)
+		}
+	}
+
+	// secp256k1 keys are stored raw, with their Ethereum address
+	bz, err := encryptWeb3Key(secp, "passphrase", ScryptKDF{N: 1024, R: 8, P: 1})
+	require.Nil(t, err, "%+v", err)
+	key, err := parseWeb3Key(bz)
+	require.Nil(t, err, "%+v", err)
+	addr, err := secp.PubKey().(crypto.PubKeySecp256k1).EthereumAddress()
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, strings.ToLower(addr.Hex()[2:]), key.Address)
+	assert.Equal(t, 64, len(key.Crypto.CipherText))
+
+	_, err = encryptWeb3Key(secp, "passphrase", DefaultKDF)
+	assert.NotNil(t, err)
+}
+
+func TestInvalidWeb3Keys(t *testing.T) {
+	// the public key must match
+	bz, err := encryptWeb3Key(crypto.GenPrivKeySecp256k1(), "passphrase", ScryptKDF{N: 1024, R: 8, P: 1})
+	require.Nil(t, err, "%+v", err)
+	key, err := parseWeb3Key(bz)
+	require.Nil(t, err, "%+v", err)
+	key.Meta.PubKey = hex.EncodeToString(crypto.GenPrivKeySecp256k1().PubKey().Bytes())
+	_, err = key.decrypt("passphrase")
+	assert.NotNil(t, err)
+
+	for i, vector := range []string{
+		strings.Replace(web3VectorScrypt, `"version": 3`, `"version": 2`, 1),
+		strings.Replace(web3VectorScrypt, `"aes-128-ctr"`, `"aes-128-cbc"`, 1),
+		strings.Replace(web3VectorScrypt, `"n": 262144`, `"n": 262145`, 1),
+		strings.Replace(web3VectorScrypt, `"n": 262144`, `"n": 1e99`, 1),
+		strings.Replace(web3VectorScrypt, `"dklen": 32`, `"dklen": 16`, 1),
+		strings.Replace(web3VectorPBKDF2, `"hmac-sha256"`, `"hmac-sha512"`, 1),
+		strings.Replace(web3VectorPBKDF2, `"kdf": "pbkdf2"`, `"kdf": "bcrypt"`, 1),
+	} {
+		key, err := parseWeb3Key([]byte(vector))
+		if err == nil {
+			_, err = key.decrypt("testpassword")
+		}
+		assert.NotNil(t, err, fmt.Sprintf("vector %d", i))
+	}
+}
@@ -0,0 +1,80 @@
+// Package merkle computes Merkle trees as specified by RFC 6962
+// (Certificate Transparency), section 2.1, and proves the inclusion of
+// one or more leaves in them.
+//
+// Leaves and inner nodes are hashed with SHA-256 under distinct
+// prefixes, so a leaf can never be passed off as an inner node:
+//
+//	leafHash(leaf)         = SHA256(0x00 || leaf)
+//	innerHash(left, right) = SHA256(0x01 || left || right)
+//
+// A tree of n > 1 items is split so that the left subtree holds the
+// largest power of two that is smaller than n. The root of an empty
+// tree is SHA256 of the empty string.
+package merkle
+
+import (
+	"crypto/sha256"
+	"math/bits"
+)
+
+var (
+	leafPrefix  = []byte{0x00}
+	innerPrefix = []byte{0x01}
+)
+
+func emptyHash() []byte {
+	h := sha256.Sum256(nil)
+	return h[:]
+}
+
+func leafHash(leaf []byte) []byte {
+	h := sha256.New()
+	h.Write(leafPrefix)
+	h.Write(leaf)
+	return h.Sum(nil)
+}
+
+func innerHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write(innerPrefix)
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// splitPoint returns the largest power of two smaller than n.
+// n must be at least 2.
+func splitPoint(n int64) int64 {
+	return 1 << uint(bits.Len64(uint64(n-1))-1)
+}
+
+// leafDepth returns the depth of the leaf at index in a tree of total
+// leaves, i.e. the number of aunts in its Proof. Proofs are checked with
+// it before recursing, since total comes from untrusted input.
+func leafDepth(index, total int64) int {
+	depth := 0
+	for total > 1 {
+		k := splitPoint(total)
+		if index < k {
+			total = k
+		} else {
+			index, total = index-k, total-k
+		}
+		depth++
+	}
+	return depth
+}
+
+// HashFromByteSlices returns the root of the tree over items.
+func HashFromByteSlices(items [][]byte) []byte {
+	switch len(items) {
+	case 0:
+		return emptyHash()
+	case 1:
+		return leafHash(items[0])
+	default:
+		k := splitPoint(int64(len(items)))
+		return innerHash(HashFromByteSlices(items[:k]), HashFromByteSlices(items[k:]))
+	}
+}
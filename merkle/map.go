@@ -0,0 +1,64 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+)
+
+// The tree of a map has one leaf per key/value pair, in ascending key
+// order. A leaf is KVPair(key, value), so proofs for a map are verified
+// against KVPair(key, value) rather than the value alone.
+
+// KVPair returns the leaf of a key/value pair:
+// uvarint(len(key)) || key || uvarint(32) || SHA256(value).
+func KVPair(key string, value []byte) []byte {
+	valueHash := sha256.Sum256(value)
+	buf := make([]byte, 0, 2*binary.MaxVarintLen64+len(key)+len(valueHash))
+	buf = appendByteSlice(buf, []byte(key))
+	return appendByteSlice(buf, valueHash[:])
+}
+
+func appendByteSlice(buf, bz []byte) []byte {
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(bz)))
+	buf = append(buf, lenBuf[:n]...)
+	return append(buf, bz...)
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func mapLeaves(m map[string][]byte) ([]string, [][]byte) {
+	keys := sortedKeys(m)
+	leaves := make([][]byte, len(keys))
+	for i, key := range keys {
+		leaves[i] = KVPair(key, m[key])
+	}
+	return keys, leaves
+}
+
+// HashFromMap returns the root of the tree over the pairs of m.
+func HashFromMap(m map[string][]byte) []byte {
+	_, leaves := mapLeaves(m)
+	return HashFromByteSlices(leaves)
+}
+
+// ProofsFromMap returns the root of the tree over the pairs of m and
+// an inclusion proof for every key.
+func ProofsFromMap(m map[string][]byte) (root []byte, proofs map[string]*Proof) {
+	keys, leaves := mapLeaves(m)
+	root, list := ProofsFromByteSlices(leaves)
+	proofs = make(map[string]*Proof, len(keys))
+	for i, key := range keys {
+		proofs[key] = list[i]
+	}
+	return root, proofs
+}
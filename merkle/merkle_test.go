@@ -0,0 +1,282 @@
+package merkle
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// leaves of the Certificate Transparency reference tests
+var ctLeaves = []string{
+	"",
+	"00",
+	"10",
+	"2021",
+	"3031",
+	"40414243",
+	"5051525354555657",
+	"606162636465666768696a6b6c6d6e6f",
+}
+
+func testItems(t *testing.T, n int) [][]byte {
+	items := make([][]byte, n)
+	for i := range items {
+		items[i] = []byte(fmt.Sprintf("item %d", i))
+	}
+	return items
+}
+
+func TestKnownRoots(t *testing.T) {
+	items := make([][]byte, len(ctLeaves))
+	for i, s := range ctLeaves {
+		bz, err := hex.DecodeString(s)
+		require.Nil(t, err, "%+v", err)
+		items[i] = bz
+	}
+	cases := []struct {
+		n    int
+		root string
+	}{
+		{0, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{1, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d"},
+		{8, "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328"},
+	}
+	for _, tc := range cases {
+		root := HashFromByteSlices(items[:tc.n])
+		assert.Equal(t, tc.root, hex.EncodeToString(root), "%d leaves", tc.n)
+	}
+}
+
+func TestProofs(t *testing.T) {
+	for n := 1; n <= 33; n++ {
+		items := testItems(t, n)
+		root, proofs := ProofsFromByteSlices(items)
+		require.Equal(t, HashFromByteSlices(items), root)
+		for i, proof := range proofs {
+			require.Nil(t, proof.Verify(root, items[i]), "%d/%d", i, n)
+
+			// wrong leaf, root, index or aunts
+			assert.NotNil(t, proof.Verify(root, []byte("other")))
+			if n > 1 {
+				assert.NotNil(t, proof.Verify(leafHash(items[i]), items[i]))
+
+				other := *proof
+				other.Index = (other.Index + 1) % other.Total
+				assert.NotNil(t, other.Verify(root, items[i]))
+
+				other = *proof
+				other.Aunts = other.Aunts[1:]
+				assert.NotNil(t, other.Verify(root, items[i]))
+			}
+			other := *proof
+			other.Aunts = append(other.Aunts, root)
+			assert.NotNil(t, other.Verify(root, items[i]))
+		}
+	}
+}
+
+func TestInvalidProofs(t *testing.T) {
+	items := testItems(t, 5)
+	root, proofs := ProofsFromByteSlices(items)
+
+	for _, proof := range []Proof{
+		{Total: 0, Index: 0},
+		{Total: 5, Index: 5, Aunts: proofs[4].Aunts},
+		{Total: 5, Index: -1, Aunts: proofs[0].Aunts},
+	} {
+		assert.NotNil(t, proof.Verify(root, items[0]), "%v", proof)
+	}
+
+	// an inner node is not a leaf
+	_, twoProofs := ProofsFromByteSlices(items[:2])
+	inner := innerHash(leafHash(items[0]), leafHash(items[1]))
+	assert.NotNil(t, twoProofs[0].Verify(HashFromByteSlices(items[:2]), inner))
+}
+
+func TestSplitPoint(t *testing.T) {
+	for n, k := range map[int64]int64{
+		2: 1, 3: 2, 4: 2, 5: 4, 8: 4, 9: 8,
+		1<<62 + 1:     1 << 62,
+		math.MaxInt64: 1 << 62,
+	} {
+		assert.Equal(t, k, splitPoint(n), "%d", n)
+	}
+}
+
+// Totals near 2^63 must fail quickly, not hang or overflow.
+func TestHugeTotal(t *testing.T) {
+	items := testItems(t, 2)
+	root := HashFromByteSlices(items)
+	hash := leafHash(items[1])
+	many := make([][]byte, 64)
+	for i := range many {
+		many[i] = hash
+	}
+
+	for _, total := range []int64{1<<62 + 1, math.MaxInt64} {
+		for _, aunts := range [][][]byte{{hash}, many} {
+			proof := Proof{Total: total, Index: 0, Aunts: aunts}
+			assert.NotNil(t, proof.Verify(root, items[0]), "%d/%d", total, len(aunts))
+			multi := MultiProof{Total: total, Indices: []int64{0}, Hashes: aunts}
+			assert.NotNil(t, multi.Verify(root, items[:1]), "%d/%d", total, len(aunts))
+			rng := RangeProof{Total: total, Start: 0, Hashes: aunts}
+			assert.NotNil(t, rng.Verify(root, items[:1]), "%d/%d", total, len(aunts))
+		}
+	}
+
+	// the last leaf of 2^62+1 has a single aunt
+	assert.Equal(t, 1, leafDepth(1<<62, 1<<62+1))
+	assert.Equal(t, 63, leafDepth(0, math.MaxInt64))
+	proof := Proof{Total: 1<<62 + 1, Index: 1 << 62, Aunts: [][]byte{hash}}
+	assert.NotNil(t, proof.Verify(root, items[0]))
+}
+
+func TestMapProofs(t *testing.T) {
+	m := map[string][]byte{
+		"foo":  []byte("bar"),
+		"baz":  []byte("qux"),
+		"zz":   nil,
+		"quux": []byte("corge"),
+	}
+	root, proofs := ProofsFromMap(m)
+	assert.Equal(t, HashFromMap(m), root)
+	require.Len(t, proofs, len(m))
+	for key, value := range m {
+		assert.Nil(t, proofs[key].Verify(root, KVPair(key, value)), key)
+		assert.NotNil(t, proofs[key].Verify(root, KVPair(key, []byte("other"))), key)
+		assert.NotNil(t, proofs[key].Verify(root, value), key)
+	}
+	// keys are sorted
+	assert.Equal(t, int64(0), proofs["baz"].Index)
+	assert.Equal(t, int64(3), proofs["zz"].Index)
+}
+
+func TestMultiProofs(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		items := testItems(t, n)
+		// every subset of up to 4 leaves, and some bigger ones
+		subsets := [][]int64{}
+		for mask := 1; mask < 1<<uint(n) && mask < 1<<10; mask++ {
+			var indices []int64
+			for i := 0; i < n; i++ {
+				if mask&(1<<uint(i)) != 0 {
+					indices = append(indices, int64(i))
+				}
+			}
+			subsets = append(subsets, indices)
+		}
+		for _, indices := range subsets {
+			root, proof, err := MultiProofFromByteSlices(items, indices)
+			require.Nil(t, err, "%+v", err)
+			require.Equal(t, HashFromByteSlices(items), root)
+
+			leaves := make([][]byte, len(indices))
+			for i, index := range indices {
+				leaves[i] = items[index]
+			}
+			require.Nil(t, proof.Verify(root, leaves), "%v/%d", indices, n)
+
+			// wrong leaf, missing or extra leaf, wrong indices
+			bad := append([][]byte{}, leaves...)
+			bad[0] = []byte("other")
+			assert.NotNil(t, proof.Verify(root, bad))
+			assert.NotNil(t, proof.Verify(root, leaves[1:]))
+			assert.NotNil(t, proof.Verify(root, append(leaves, items[0])))
+			if len(proof.Hashes) > 0 {
+				other := *proof
+				other.Hashes = other.Hashes[1:]
+				assert.NotNil(t, other.Verify(root, leaves))
+			}
+			other := *proof
+			other.Hashes = append(other.Hashes, root)
+			assert.NotNil(t, other.Verify(root, leaves))
+		}
+	}
+}
+
+func TestMultiProofIsSmaller(t *testing.T) {
+	items := testItems(t, 64)
+	indices := []int64{0, 1, 2, 3, 32, 33}
+	_, proof, err := MultiProofFromByteSlices(items, indices)
+	require.Nil(t, err, "%+v", err)
+	// 3 + 4 subtrees next to the two proven ranges, instead of 6 * 6 aunts
+	assert.Len(t, proof.Hashes, 7)
+}
+
+func TestInvalidMultiProofs(t *testing.T) {
+	items := testItems(t, 8)
+	for _, indices := range [][]int64{nil, {8}, {-1}, {1, 1}} {
+		_, _, err := MultiProofFromByteSlices(items, indices)
+		assert.NotNil(t, err, "%v", indices)
+	}
+
+	// indices are sorted for the proof
+	root, proof, err := MultiProofFromByteSlices(items, []int64{5, 2})
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, []int64{2, 5}, proof.Indices)
+	assert.Nil(t, proof.Verify(root, [][]byte{items[2], items[5]}))
+
+	// but must be sorted in the proof
+	proof.Indices = []int64{5, 2}
+	assert.NotNil(t, proof.Verify(root, [][]byte{items[5], items[2]}))
+
+	// and there must be at least one
+	empty := MultiProof{Total: 8, Hashes: [][]byte{root}}
+	assert.NotNil(t, empty.Verify(root, nil))
+}
+
+func TestRangeProofs(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		items := testItems(t, n)
+		for start := 0; start < n; start++ {
+			for end := start + 1; end <= n; end++ {
+				root, proof, err := RangeProofFromByteSlices(items, int64(start), int64(end))
+				require.Nil(t, err, "%+v", err)
+				require.Equal(t, HashFromByteSlices(items), root)
+				leaves := items[start:end]
+				require.Nil(t, proof.Verify(root, leaves), "[%d, %d)/%d", start, end, n)
+
+				bad := append([][]byte{}, leaves...)
+				bad[len(bad)-1] = []byte("other")
+				assert.NotNil(t, proof.Verify(root, bad))
+				if len(leaves) > 1 {
+					assert.NotNil(t, proof.Verify(root, leaves[:len(leaves)-1]))
+				}
+				if end < n {
+					assert.NotNil(t, proof.Verify(root, items[start:end+1]))
+				}
+			}
+		}
+	}
+
+	items := testItems(t, 8)
+	for _, r := range [][2]int64{{-1, 2}, {3, 3}, {4, 2}, {0, 9}} {
+		_, _, err := RangeProofFromByteSlices(items, r[0], r[1])
+		assert.NotNil(t, err, "%v", r)
+	}
+}
+
+func TestProofEncoding(t *testing.T) {
+	items := testItems(t, 7)
+	root, proofs := ProofsFromByteSlices(items)
+	var proof Proof
+	require.Nil(t, cdc.UnmarshalBinaryBare(proofs[3].Bytes(), &proof))
+	assert.Equal(t, *proofs[3], proof)
+	assert.Nil(t, proof.Verify(root, items[3]))
+
+	_, multi, err := MultiProofFromByteSlices(items, []int64{1, 6})
+	require.Nil(t, err, "%+v", err)
+	var multi2 MultiProof
+	require.Nil(t, cdc.UnmarshalBinaryBare(multi.Bytes(), &multi2))
+	assert.Nil(t, multi2.Verify(root, [][]byte{items[1], items[6]}))
+
+	_, rng, err := RangeProofFromByteSlices(items, 2, 5)
+	require.Nil(t, err, "%+v", err)
+	var rng2 RangeProof
+	require.Nil(t, cdc.UnmarshalBinaryBare(rng.Bytes(), &rng2))
+	assert.Nil(t, rng2.Verify(root, items[2:5]))
+}
@@ -0,0 +1,221 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// MultiProof and RangeProof prove the inclusion of several leaves at
+// once. They hold the hashes of the maximal subtrees that contain none
+// of the proven leaves, in depth first, left to right order, which is
+// less than the aunts of one Proof per leaf.
+
+// leafSet tells which leaves of a tree are proven.
+type leafSet interface {
+	// overlaps returns true iff a leaf in [lo, hi) is proven.
+	overlaps(lo, hi int64) bool
+}
+
+type indexSet []int64 // sorted, unique
+
+func (s indexSet) overlaps(lo, hi int64) bool {
+	i := sort.Search(len(s), func(i int) bool { return s[i] >= lo })
+	return i < len(s) && s[i] < hi
+}
+
+type rangeSet struct{ start, end int64 }
+
+func (s rangeSet) overlaps(lo, hi int64) bool {
+	return lo < s.end && s.start < hi
+}
+
+// partialHashes returns the root of the tree over items, which start at
+// index lo, and appends the hashes of the subtrees without proven
+// leaves to hashes.
+func partialHashes(items [][]byte, lo int64, set leafSet, hashes *[][]byte) []byte {
+	hi := lo + int64(len(items))
+	if !set.overlaps(lo, hi) {
+		root := HashFromByteSlices(items)
+		*hashes = append(*hashes, root)
+		return root
+	}
+	if len(items) == 1 {
+		return leafHash(items[0])
+	}
+	k := splitPoint(int64(len(items)))
+	left := partialHashes(items[:k], lo, set, hashes)
+	right := partialHashes(items[k:], lo+k, set, hashes)
+	return innerHash(left, right)
+}
+
+// partialRoot is the inverse of partialHashes: it recomputes the root
+// of the tree of leaves [lo, hi) from the proven leaves and the subtree
+// hashes, consuming both.
+func partialRoot(lo, hi int64, set leafSet, leaves, hashes *[][]byte) ([]byte, error) {
+	if !set.overlaps(lo, hi) {
+		if len(*hashes) == 0 {
+			return nil, errors.New("merkle: not enough hashes in proof")
+		}
+		h := (*hashes)[0]
+		*hashes = (*hashes)[1:]
+		return h, nil
+	}
+	if hi-lo == 1 {
+		if len(*leaves) == 0 {
+			return nil, errors.New("merkle: not enough leaves for proof")
+		}
+		leaf := (*leaves)[0]
+		*leaves = (*leaves)[1:]
+		return leafHash(leaf), nil
+	}
+	k := splitPoint(hi - lo)
+	left, err := partialRoot(lo, lo+k, set, leaves, hashes)
+	if err != nil {
+		return nil, err
+	}
+	right, err := partialRoot(lo+k, hi, set, leaves, hashes)
+	if err != nil {
+		return nil, err
+	}
+	return innerHash(left, right), nil
+}
+
+// verifyPartial checks a MultiProof or RangeProof whose first proven
+// leaf is at first. The siblings on its path are disjoint subtrees, so
+// the proof has at least one hash or leaf more than its depth.
+func verifyPartial(root []byte, total, first int64, set leafSet, leaves, hashes [][]byte) error {
+	if total <= 0 {
+		return errors.New("merkle: proof total must be positive")
+	}
+	if leafDepth(first, total) >= len(leaves)+len(hashes) {
+		return errors.New("merkle: not enough hashes in proof")
+	}
+	computed, err := partialRoot(0, total, set, &leaves, &hashes)
+	if err != nil {
+		return err
+	}
+	if len(leaves) != 0 {
+		return errors.New("merkle: too many leaves for proof")
+	}
+	if len(hashes) != 0 {
+		return errors.New("merkle: too many hashes in proof")
+	}
+	if !bytes.Equal(computed, root) {
+		return errors.New("merkle: root hash mismatch")
+	}
+	return nil
+}
+
+//-------------------------------------
+
+// MultiProof proves the inclusion of the leaves at Indices.
+type MultiProof struct {
+	Total   int64    `json:"total"`
+	Indices []int64  `json:"indices"`
+	Hashes  [][]byte `json:"hashes"`
+}
+
+// MultiProofFromByteSlices returns the root of the tree over items and
+// a proof for the items at the given indices.
+func MultiProofFromByteSlices(items [][]byte, indices []int64) (root []byte, proof *MultiProof, err error) {
+	set, err := newIndexSet(indices, int64(len(items)))
+	if err != nil {
+		return nil, nil, err
+	}
+	proof = &MultiProof{Total: int64(len(items)), Indices: set}
+	root = partialHashes(items, 0, set, &proof.Hashes)
+	return root, proof, nil
+}
+
+func newIndexSet(indices []int64, total int64) (indexSet, error) {
+	if len(indices) == 0 {
+		return nil, errors.New("merkle: no indices to prove")
+	}
+	set := make(indexSet, len(indices))
+	copy(set, indices)
+	sort.Slice(set, func(i, j int) bool { return set[i] < set[j] })
+	for i, index := range set {
+		if index < 0 || index >= total {
+			return nil, fmt.Errorf("merkle: index %d out of range [0, %d)", index, total)
+		}
+		if i > 0 && set[i-1] == index {
+			return nil, fmt.Errorf("merkle: duplicate index %d", index)
+		}
+	}
+	return set, nil
+}
+
+// Verify returns nil iff the proof shows that leaves are included in
+// the tree with the given root. leaves[i] is the leaf at Indices[i].
+func (mp *MultiProof) Verify(root []byte, leaves [][]byte) error {
+	if len(leaves) != len(mp.Indices) {
+		return fmt.Errorf("merkle: expected %d leaves, got %d", len(mp.Indices), len(leaves))
+	}
+	if len(mp.Indices) == 0 {
+		return errors.New("merkle: no indices to prove")
+	}
+	for i, index := range mp.Indices {
+		if index < 0 || index >= mp.Total || (i > 0 && mp.Indices[i-1] >= index) {
+			return errors.New("merkle: indices must be sorted, unique and in range")
+		}
+	}
+	return verifyPartial(root, mp.Total, mp.Indices[0], indexSet(mp.Indices), leaves, mp.Hashes)
+}
+
+func (mp *MultiProof) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(mp)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (mp *MultiProof) String() string {
+	return fmt.Sprintf("MultiProof{%v/%d, %d hashes}", mp.Indices, mp.Total, len(mp.Hashes))
+}
+
+//-------------------------------------
+
+// RangeProof proves the inclusion of consecutive leaves, starting at
+// Start.
+type RangeProof struct {
+	Total  int64    `json:"total"`
+	Start  int64    `json:"start"`
+	Hashes [][]byte `json:"hashes"`
+}
+
+// RangeProofFromByteSlices returns the root of the tree over items and
+// a proof for items[start:end].
+func RangeProofFromByteSlices(items [][]byte, start, end int64) (root []byte, proof *RangeProof, err error) {
+	total := int64(len(items))
+	if start < 0 || start >= end || end > total {
+		return nil, nil, fmt.Errorf("merkle: invalid range [%d, %d) of %d items", start, end, total)
+	}
+	proof = &RangeProof{Total: total, Start: start}
+	root = partialHashes(items, 0, rangeSet{start, end}, &proof.Hashes)
+	return root, proof, nil
+}
+
+// Verify returns nil iff the proof shows that leaves are the leaves
+// [Start, Start+len(leaves)) of the tree with the given root.
+func (rp *RangeProof) Verify(root []byte, leaves [][]byte) error {
+	end := rp.Start + int64(len(leaves))
+	if len(leaves) == 0 || rp.Start < 0 || end > rp.Total {
+		return fmt.Errorf("merkle: invalid range [%d, %d) of %d leaves", rp.Start, end, rp.Total)
+	}
+	return verifyPartial(root, rp.Total, rp.Start, rangeSet{rp.Start, end}, leaves, rp.Hashes)
+}
+
+func (rp *RangeProof) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(rp)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (rp *RangeProof) String() string {
+	return fmt.Sprintf("RangeProof{%d+/%d, %d hashes}", rp.Start, rp.Total, len(rp.Hashes))
+}
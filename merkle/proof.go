@@ -0,0 +1,123 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Proof proves the inclusion of the leaf at Index in a tree of Total
+// leaves. Aunts are the hashes of the sibling subtrees on the path from
+// the leaf to the root, starting at the bottom.
+type Proof struct {
+	Total int64    `json:"total"`
+	Index int64    `json:"index"`
+	Aunts [][]byte `json:"aunts"`
+}
+
+// ProofsFromByteSlices returns the root of the tree over items and an
+// inclusion proof for every item.
+func ProofsFromByteSlices(items [][]byte) (root []byte, proofs []*Proof) {
+	total := int64(len(items))
+	proofs = make([]*Proof, total)
+	for i := range proofs {
+		proofs[i] = &Proof{Total: total, Index: int64(i)}
+	}
+	root = buildAunts(items, proofs)
+	return root, proofs
+}
+
+// buildAunts returns the root of the tree over items and appends the
+// sibling hashes to the proofs of the corresponding items.
+func buildAunts(items [][]byte, proofs []*Proof) []byte {
+	switch len(items) {
+	case 0:
+		return emptyHash()
+	case 1:
+		return leafHash(items[0])
+	default:
+		k := splitPoint(int64(len(items)))
+		left := buildAunts(items[:k], proofs[:k])
+		right := buildAunts(items[k:], proofs[k:])
+		for _, p := range proofs[:k] {
+			p.Aunts = append(p.Aunts, right)
+		}
+		for _, p := range proofs[k:] {
+			p.Aunts = append(p.Aunts, left)
+		}
+		return innerHash(left, right)
+	}
+}
+
+// Verify returns nil iff the proof shows that leaf is included in the
+// tree with the given root.
+func (p *Proof) Verify(root []byte, leaf []byte) error {
+	computed, err := p.ComputeRootHash(leaf)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(computed, root) {
+		return errors.New("merkle: root hash mismatch")
+	}
+	return nil
+}
+
+// ComputeRootHash returns the root of the tree implied by the proof
+// for leaf.
+func (p *Proof) ComputeRootHash(leaf []byte) ([]byte, error) {
+	if p.Total <= 0 {
+		return nil, errors.New("merkle: proof total must be positive")
+	}
+	if p.Index < 0 || p.Index >= p.Total {
+		return nil, fmt.Errorf("merkle: proof index %d out of range [0, %d)", p.Index, p.Total)
+	}
+	if leafDepth(p.Index, p.Total) != len(p.Aunts) {
+		return nil, errors.New("merkle: invalid number of aunts")
+	}
+	root := computeHashFromAunts(p.Index, p.Total, leafHash(leaf), p.Aunts)
+	if root == nil {
+		return nil, errors.New("merkle: invalid number of aunts")
+	}
+	return root, nil
+}
+
+// computeHashFromAunts walks down from the root to the leaf at index,
+// and hashes the path back up. It returns nil if the number of aunts
+// doesn't match the depth of the leaf.
+func computeHashFromAunts(index, total int64, leafHash []byte, aunts [][]byte) []byte {
+	if total == 1 {
+		if len(aunts) != 0 {
+			return nil
+		}
+		return leafHash
+	}
+	if len(aunts) == 0 {
+		return nil
+	}
+	k := splitPoint(total)
+	last := aunts[len(aunts)-1]
+	if index < k {
+		left := computeHashFromAunts(index, k, leafHash, aunts[:len(aunts)-1])
+		if left == nil {
+			return nil
+		}
+		return innerHash(left, last)
+	}
+	right := computeHashFromAunts(index-k, total-k, leafHash, aunts[:len(aunts)-1])
+	if right == nil {
+		return nil
+	}
+	return innerHash(last, right)
+}
+
+func (p *Proof) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(p)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (p *Proof) String() string {
+	return fmt.Sprintf("Proof{%d/%d, %d aunts}", p.Index, p.Total, len(p.Aunts))
+}
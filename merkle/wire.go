@@ -0,0 +1,21 @@
+package merkle
+
+import (
+	amino "github.com/tendermint/go-amino"
+)
+
+var cdc = amino.NewCodec()
+
+func init() {
+	RegisterAmino(cdc)
+}
+
+// RegisterAmino registers the proof types with cdc.
+func RegisterAmino(cdc *amino.Codec) {
+	cdc.RegisterConcrete(&Proof{},
+		"tendermint/MerkleProof", nil)
+	cdc.RegisterConcrete(&MultiProof{},
+		"tendermint/MerkleMultiProof", nil)
+	cdc.RegisterConcrete(&RangeProof{},
+		"tendermint/MerkleRangeProof", nil)
+}
@@ -0,0 +1,199 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+//-------------------------------------
+
+var _ PubKey = PubKeyMultisigThreshold{}
+
+// PubKeyMultisigThreshold implements a K of N threshold multisig.
+// The order of PubKeys is significant: it determines the address and
+// the position of each member in the SignatureMultisig bit array.
+type PubKeyMultisigThreshold struct {
+	K       uint     `json:"threshold"`
+	PubKeys []PubKey `json:"pubkeys"`
+}
+
+// NewPubKeyMultisigThreshold returns a new PubKeyMultisigThreshold.
+// Panics if len(pubkeys) < k or 0 >= k.
+func NewPubKeyMultisigThreshold(k int, pubkeys []PubKey) PubKey {
+	if k <= 0 {
+		panic("threshold k of n multisignature: k <= 0")
+	}
+	if len(pubkeys) < k {
+		panic("threshold k of n multisignature: len(pubkeys) < k")
+	}
+	return PubKeyMultisigThreshold{uint(k), pubkeys}
+}
+
+// Address is the RIPEMD160 of the amino encoding of the multisig,
+// so it commits to both the threshold and the ordered member set.
+func (pubKey PubKeyMultisigThreshold) Address() Address {
+	return Address(Ripemd160(pubKey.Bytes()))
+}
+
+func (pubKey PubKeyMultisigThreshold) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(pubKey)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+// VerifyBytes expects sig to be a SignatureMultisig, or a pointer to
+// one, like NewSignatureMultisig returns.
+// It returns true iff at least K of the member keys have a valid
+// signature over msg, and no invalid signatures are included.
+func (pubKey PubKeyMultisigThreshold) VerifyBytes(msg []byte, sig_ Signature) bool {
+	sig, ok := toSignatureMultisig(sig_)
+	if !ok || !sig.BitArray.isValid() {
+		return false
+	}
+	// the key may be decoded rather than built by the constructor
+	if pubKey.K == 0 || int(pubKey.K) > len(pubKey.PubKeys) {
+		return false
+	}
+	size := sig.BitArray.Size()
+	// ensure bit array is the correct size
+	if len(pubKey.PubKeys) != size {
+		return false
+	}
+	// ensure size of signature list
+	if len(sig.Sigs) < int(pubKey.K) || len(sig.Sigs) > size {
+		return false
+	}
+	// ensure at least k signatures are set
+	if sig.BitArray.NumTrueBitsBefore(size) < int(pubKey.K) {
+		return false
+	}
+	// index in the list of signatures which we are concerned with.
+	sigIndex := 0
+	for i := 0; i < size; i++ {
+		if sig.BitArray.GetIndex(i) {
+			if sigIndex >= len(sig.Sigs) {
+				return false
+			}
+			if !pubKey.PubKeys[i].VerifyBytes(msg, sig.Sigs[sigIndex]) {
+				return false
+			}
+			sigIndex++
+		}
+	}
+	// every included signature must belong to a set bit
+	return sigIndex == len(sig.Sigs)
+}
+
+func (pubKey PubKeyMultisigThreshold) String() string {
+	return fmt.Sprintf("PubKeyMultisigThreshold{%d/%d %X}", pubKey.K, len(pubKey.PubKeys), pubKey.Address())
+}
+
+// Equals returns true iff pubKey and other both have identical
+// thresholds and the same member keys in the same order.
+func (pubKey PubKeyMultisigThreshold) Equals(other PubKey) bool {
+	otherMulti, ok := other.(PubKeyMultisigThreshold)
+	if !ok {
+		return false
+	}
+	if pubKey.K != otherMulti.K || len(pubKey.PubKeys) != len(otherMulti.PubKeys) {
+		return false
+	}
+	for i := 0; i < len(pubKey.PubKeys); i++ {
+		if !pubKey.PubKeys[i].Equals(otherMulti.PubKeys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+//-------------------------------------
+
+var _ Signature = SignatureMultisig{}
+
+// SignatureMultisig is the signature for a PubKeyMultisigThreshold.
+// BitArray marks which members signed, and Sigs holds their
+// signatures in the same order as the members.
+type SignatureMultisig struct {
+	BitArray *CompactBitArray `json:"bit_array"`
+	Sigs     []Signature      `json:"sigs"`
+}
+
+// NewSignatureMultisig returns an empty multisignature
+// for a multisig with n members.
+func NewSignatureMultisig(n int) *SignatureMultisig {
+	return &SignatureMultisig{BitArray: NewCompactBitArray(n)}
+}
+
+// AddSignature adds a signature to the multisig at the corresponding
+// index. If a signature already exists at that index, it is replaced.
+func (sig *SignatureMultisig) AddSignature(s Signature, index int) error {
+	if index < 0 || index >= sig.BitArray.Size() {
+		return fmt.Errorf("index %d out of range for multisig of size %d", index, sig.BitArray.Size())
+	}
+	newSigIndex := sig.BitArray.NumTrueBitsBefore(index)
+	// Signature already exists, just replace the value there
+	if sig.BitArray.GetIndex(index) {
+		sig.Sigs[newSigIndex] = s
+		return nil
+	}
+	sig.BitArray.SetIndex(index, true)
+	// Insert the signature at the right position
+	sig.Sigs = append(sig.Sigs, nil)
+	copy(sig.Sigs[newSigIndex+1:], sig.Sigs[newSigIndex:])
+	sig.Sigs[newSigIndex] = s
+	return nil
+}
+
+// AddSignatureFromPubKey adds a signature to the multisig at the index
+// of pubkey within keys.
+func (sig *SignatureMultisig) AddSignatureFromPubKey(s Signature, pubkey PubKey, keys []PubKey) error {
+	for i, key := range keys {
+		if key.Equals(pubkey) {
+			return sig.AddSignature(s, i)
+		}
+	}
+	return errors.New("provided key didn't exist in pubkeys")
+}
+
+func (sig SignatureMultisig) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(sig)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (sig SignatureMultisig) IsZero() bool { return len(sig.Sigs) == 0 }
+
+func (sig SignatureMultisig) String() string {
+	return fmt.Sprintf("/%v %X.../", sig.BitArray, cmn.Fingerprint(sig.Bytes()))
+}
+
+func (sig SignatureMultisig) Equals(other Signature) bool {
+	if otherMulti, ok := toSignatureMultisig(other); ok {
+		return bytes.Equal(sig.Bytes(), otherMulti.Bytes())
+	} else {
+		return false
+	}
+}
+
+// toSignatureMultisig returns sig as a SignatureMultisig, whether it's
+// one or a non-nil pointer to one.
+func toSignatureMultisig(sig Signature) (SignatureMultisig, bool) {
+	switch sig := sig.(type) {
+	case SignatureMultisig:
+		return sig, true
+	case *SignatureMultisig:
+		if sig == nil {
+			return SignatureMultisig{}, false
+		}
+		return *sig, true
+	default:
+		return SignatureMultisig{}, false
+	}
+}
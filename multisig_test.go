@@ -0,0 +1,171 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generatePubKeysAndSignatures(n int, msg []byte) (pubkeys []PubKey, signatures []Signature) {
+	pubkeys = make([]PubKey, n)
+	signatures = make([]Signature, n)
+	for i := 0; i < n; i++ {
+		var privkey PrivKey
+		if i%2 == 0 {
+			privkey = GenPrivKeyEd25519()
+		} else {
+			privkey = GenPrivKeySecp256k1()
+		}
+		pubkeys[i] = privkey.PubKey()
+		signatures[i] = MustSign(privkey, msg)
+	}
+	return
+}
+
+func TestThresholdMultisigValidCases(t *testing.T) {
+	msg := []byte{1, 2, 3, 4}
+	pubkeys, sigs := generatePubKeysAndSignatures(5, msg)
+	cases := []struct {
+		k                int
+		signingIndices   []int
+		passAfterKSigs   bool
+		passAfterAllSigs bool
+	}{
+		{2, []int{0, 4, 1}, true, true},
+		{3, []int{2, 0, 4}, true, true},
+		{5, []int{0, 1, 2, 3, 4}, true, true},
+		{4, []int{3, 1}, false, false},
+	}
+
+	for i, tc := range cases {
+		multisigKey := NewPubKeyMultisigThreshold(tc.k, pubkeys)
+		multisig := NewSignatureMultisig(len(pubkeys))
+		for j, idx := range tc.signingIndices {
+			require.False(t, multisigKey.VerifyBytes(msg, *multisig),
+				"case %d: multisig passed with only %d sigs", i, j)
+			err := multisig.AddSignatureFromPubKey(sigs[idx], pubkeys[idx], pubkeys)
+			require.Nil(t, err, "case %d: %+v", i, err)
+			if j+1 >= tc.k {
+				break
+			}
+		}
+		assert.Equal(t, tc.passAfterKSigs, multisigKey.VerifyBytes(msg, *multisig), "case %d", i)
+
+		for _, idx := range tc.signingIndices {
+			err := multisig.AddSignatureFromPubKey(sigs[idx], pubkeys[idx], pubkeys)
+			require.Nil(t, err, "case %d: %+v", i, err)
+		}
+		assert.Equal(t, tc.passAfterAllSigs, multisigKey.VerifyBytes(msg, *multisig), "case %d", i)
+		// the constructor's pointer verifies the same
+		assert.Equal(t, tc.passAfterAllSigs, multisigKey.VerifyBytes(msg, multisig), "case %d", i)
+	}
+}
+
+func TestThresholdMultisigPointer(t *testing.T) {
+	msg := []byte{1, 2, 3, 4}
+	pubkeys, sigs := generatePubKeysAndSignatures(3, msg)
+	multisigKey := NewPubKeyMultisigThreshold(2, pubkeys)
+
+	var multisig Signature = NewSignatureMultisig(len(pubkeys))
+	assert.False(t, multisigKey.VerifyBytes(msg, multisig))
+	require.Nil(t, multisig.(*SignatureMultisig).AddSignature(sigs[0], 0))
+	require.Nil(t, multisig.(*SignatureMultisig).AddSignature(sigs[1], 1))
+	assert.True(t, multisigKey.VerifyBytes(msg, multisig))
+	assert.True(t, multisigKey.VerifyBytes(msg, *multisig.(*SignatureMultisig)))
+	assert.True(t, multisig.Equals(*multisig.(*SignatureMultisig)))
+	assert.True(t, (*multisig.(*SignatureMultisig)).Equals(multisig))
+
+	var nilSig *SignatureMultisig
+	assert.False(t, multisigKey.VerifyBytes(msg, nilSig))
+	assert.False(t, multisig.Equals(nilSig))
+}
+
+func TestThresholdMultisigInvalidCases(t *testing.T) {
+	msg := []byte{1, 2, 3, 4}
+	pubkeys, sigs := generatePubKeysAndSignatures(5, msg)
+	multisigKey := NewPubKeyMultisigThreshold(2, pubkeys)
+
+	// signature from the wrong member
+	multisig := NewSignatureMultisig(len(pubkeys))
+	require.Nil(t, multisig.AddSignature(sigs[0], 0))
+	require.Nil(t, multisig.AddSignature(sigs[1], 2))
+	assert.False(t, multisigKey.VerifyBytes(msg, *multisig))
+
+	// bit array of the wrong size
+	multisig = NewSignatureMultisig(len(pubkeys) + 1)
+	require.Nil(t, multisig.AddSignature(sigs[0], 0))
+	require.Nil(t, multisig.AddSignature(sigs[1], 1))
+	assert.False(t, multisigKey.VerifyBytes(msg, *multisig))
+
+	// index out of range
+	multisig = NewSignatureMultisig(len(pubkeys))
+	assert.NotNil(t, multisig.AddSignature(sigs[0], len(pubkeys)))
+	assert.NotNil(t, multisig.AddSignatureFromPubKey(sigs[0], GenPrivKeyEd25519().PubKey(), pubkeys))
+
+	// not a multisignature
+	assert.False(t, multisigKey.VerifyBytes(msg, sigs[0]))
+}
+
+func TestThresholdMultisigMalformed(t *testing.T) {
+	msg := []byte{1, 2, 3, 4}
+	pubkeys, sigs := generatePubKeysAndSignatures(9, msg)
+	multisigKey := NewPubKeyMultisigThreshold(2, pubkeys)
+
+	// ExtraBitsStored >= 8 claims 9 bits in a single byte
+	for _, bA := range []*CompactBitArray{
+		{ExtraBitsStored: 9, Elems: []byte{0xff}},
+		{ExtraBitsStored: 255, Elems: []byte{0xff, 0xff}},
+		{ExtraBitsStored: 1, Elems: nil},
+	} {
+		multisig := SignatureMultisig{BitArray: bA, Sigs: sigs}
+		// as decoded from untrusted bytes
+		decoded, err := SignatureFromBytes(multisig.Bytes())
+		require.Nil(t, err, "%+v", err)
+		assert.NotPanics(t, func() {
+			assert.False(t, multisigKey.VerifyBytes(msg, decoded), "%v", bA)
+		})
+		assert.Equal(t, 0, bA.Size())
+		assert.False(t, bA.GetIndex(8))
+	}
+
+	// thresholds the constructor rejects
+	multisig := NewSignatureMultisig(len(pubkeys))
+	for i := range pubkeys {
+		require.Nil(t, multisig.AddSignature(sigs[i], i))
+	}
+	assert.True(t, multisigKey.VerifyBytes(msg, multisig))
+	for _, k := range []uint{0, uint(len(pubkeys) + 1)} {
+		key := PubKeyMultisigThreshold{K: k, PubKeys: pubkeys}
+		assert.False(t, key.VerifyBytes(msg, multisig), "k = %d", k)
+	}
+}
+
+func TestThresholdMultisigAddress(t *testing.T) {
+	msg := []byte{1, 2, 3, 4}
+	pubkeys, _ := generatePubKeysAndSignatures(3, msg)
+
+	multisigKey := NewPubKeyMultisigThreshold(2, pubkeys)
+	assert.Equal(t, multisigKey.Address(), NewPubKeyMultisigThreshold(2, pubkeys).Address())
+	assert.NotEqual(t, multisigKey.Address(), NewPubKeyMultisigThreshold(3, pubkeys).Address())
+	reordered := []PubKey{pubkeys[1], pubkeys[0], pubkeys[2]}
+	assert.NotEqual(t, multisigKey.Address(), NewPubKeyMultisigThreshold(2, reordered).Address())
+}
+
+func TestThresholdMultisigEncoding(t *testing.T) {
+	msg := []byte{1, 2, 3, 4}
+	pubkeys, sigs := generatePubKeysAndSignatures(3, msg)
+	multisigKey := NewPubKeyMultisigThreshold(2, pubkeys)
+	multisig := NewSignatureMultisig(len(pubkeys))
+	require.Nil(t, multisig.AddSignature(sigs[2], 2))
+	require.Nil(t, multisig.AddSignature(sigs[0], 0))
+
+	pub2, err := PubKeyFromBytes(multisigKey.Bytes())
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, multisigKey.Equals(pub2))
+
+	sig2, err := SignatureFromBytes(multisig.Bytes())
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, multisig.Equals(sig2))
+	assert.True(t, pub2.VerifyBytes(msg, sig2))
+}
@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	xed25519 "golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+)
+
+// OpenSSH has no secp256k1 key type, so only Ed25519 keys can be
+// exchanged with ssh-keygen and friends.
+
+// MarshalOpenSSHPubKey returns pub in the authorized_keys format,
+// e.g. "ssh-ed25519 AAAA... comment".
+func MarshalOpenSSHPubKey(pub PubKey, comment string) ([]byte, error) {
+	pubEd, ok := pub.(PubKeyEd25519)
+	if !ok {
+		return nil, fmt.Errorf("OpenSSH is not supported for %T", pub)
+	}
+	sshPub, err := ssh.NewPublicKey(pubEd.ToStd())
+	if err != nil {
+		return nil, err
+	}
+	line := ssh.MarshalAuthorizedKey(sshPub)
+	if comment == "" {
+		return line, nil
+	}
+	// replace the trailing newline with the comment
+	line = append(line[:len(line)-1], ' ')
+	return append(line, comment+"\n"...), nil
+}
+
+// ParseOpenSSHPubKey parses a public key in the authorized_keys format,
+// as found in the .pub files written by ssh-keygen. It also returns
+// the key's comment.
+func ParseOpenSSHPubKey(data []byte) (PubKey, string, error) {
+	sshPub, comment, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, "", err
+	}
+	if sshPub.Type() != ssh.KeyAlgoED25519 {
+		return nil, "", fmt.Errorf("Unsupported OpenSSH key type %q", sshPub.Type())
+	}
+	pub, err := PubKeyFromStd(sshPub.(ssh.CryptoPublicKey).CryptoPublicKey())
+	if err != nil {
+		return nil, "", err
+	}
+	return pub, comment, nil
+}
+
+// MarshalOpenSSHPrivKey returns priv as an unencrypted
+// "OPENSSH PRIVATE KEY" PEM block, as written by ssh-keygen.
+func MarshalOpenSSHPrivKey(priv PrivKey, comment string) ([]byte, error) {
+	privEd, ok := priv.(PrivKeyEd25519)
+	if !ok {
+		return nil, fmt.Errorf("OpenSSH is not supported for %T", priv)
+	}
+	block, err := ssh.MarshalPrivateKey(privEd.ToStd(), comment)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// ParseOpenSSHPrivKey parses an "OPENSSH PRIVATE KEY" PEM block.
+// passphrase must be nil for unencrypted keys.
+func ParseOpenSSHPrivKey(data []byte, passphrase []byte) (PrivKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "OPENSSH PRIVATE KEY" {
+		return nil, errors.New("No OpenSSH private key found in PEM data")
+	}
+	var key interface{}
+	var err error
+	if passphrase == nil {
+		key, err = ssh.ParseRawPrivateKey(data)
+	} else {
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(data, passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Ed25519 keys are returned as a pointer.
+	if ptr, ok := key.(*xed25519.PrivateKey); ok {
+		key = *ptr
+	}
+	return PrivKeyFromStd(key)
+}
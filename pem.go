@@ -0,0 +1,359 @@
+package crypto
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	secp256k1 "github.com/btcsuite/btcd/btcec"
+	"github.com/tendermint/ed25519"
+)
+
+// This file implements the standard DER encodings of keys, as used by
+// openssl and most other tools:
+//
+//	PKCS#8 (RFC 5208, RFC 8410) for private keys,
+//	PKIX SubjectPublicKeyInfo (RFC 5280, RFC 8410) for public keys,
+//	SEC1 (RFC 5915) for secp256k1 private keys,
+//
+// and their PEM armored forms. The standard library's crypto/x509
+// does not know about the secp256k1 curve, so the ASN.1 structures
+// are handled here directly.
+
+var (
+	oidEd25519        = asn1.ObjectIdentifier{1, 3, 101, 112}
+	oidECPublicKey    = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidCurveSecp256k1 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+const (
+	pemTypePrivKey   = "PRIVATE KEY"
+	pemTypePubKey    = "PUBLIC KEY"
+	pemTypeECPrivKey = "EC PRIVATE KEY"
+	pemTypeECParams  = "EC PARAMETERS"
+	ecPrivKeyVersion = 1
+	ed25519SeedSize  = 32
+)
+
+type pkixAlgorithm struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+// pkcs8 is the PrivateKeyInfo of RFC 5208, or the OneAsymmetricKey of
+// RFC 5958, which adds an optional public key.
+type pkcs8 struct {
+	Version    int
+	Algorithm  pkixAlgorithm
+	PrivateKey []byte
+	Attributes asn1.RawValue  `asn1:"optional,tag:0"`
+	PublicKey  asn1.BitString `asn1:"optional,tag:1"`
+}
+
+type pkixPublicKey struct {
+	Algorithm pkixAlgorithm
+	PublicKey asn1.BitString
+}
+
+// ecPrivateKey is the ECPrivateKey of RFC 5915.
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+func secp256k1Algorithm() (pkixAlgorithm, error) {
+	params, err := asn1.Marshal(oidCurveSecp256k1)
+	if err != nil {
+		return pkixAlgorithm{}, err
+	}
+	return pkixAlgorithm{
+		Algorithm:  oidECPublicKey,
+		Parameters: asn1.RawValue{FullBytes: params},
+	}, nil
+}
+
+// checkSecp256k1Algorithm returns an error unless alg identifies an EC
+// key on the secp256k1 curve.
+func checkSecp256k1Algorithm(alg pkixAlgorithm) error {
+	var curve asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &curve); err != nil {
+		return errors.New("Missing or unsupported EC parameters")
+	}
+	if !curve.Equal(oidCurveSecp256k1) {
+		return fmt.Errorf("Unsupported EC curve %v", curve)
+	}
+	return nil
+}
+
+//-------------------------------------
+// PKCS#8
+
+// MarshalPKCS8 returns the PKCS#8 DER encoding of priv.
+func MarshalPKCS8(priv PrivKey) ([]byte, error) {
+	switch priv := priv.(type) {
+	case PrivKeyEd25519:
+		// CurvePrivateKey ::= OCTET STRING
+		seed, err := asn1.Marshal(priv[:ed25519SeedSize])
+		if err != nil {
+			return nil, err
+		}
+		return asn1.Marshal(pkcs8{
+			Algorithm:  pkixAlgorithm{Algorithm: oidEd25519},
+			PrivateKey: seed,
+		})
+	case PrivKeySecp256k1:
+		alg, err := secp256k1Algorithm()
+		if err != nil {
+			return nil, err
+		}
+		// The curve is already named by the algorithm.
+		ecKey, err := marshalSEC1(priv, false)
+		if err != nil {
+			return nil, err
+		}
+		return asn1.Marshal(pkcs8{
+			Algorithm:  alg,
+			PrivateKey: ecKey,
+		})
+	default:
+		return nil, fmt.Errorf("PKCS#8 is not supported for %T", priv)
+	}
+}
+
+// ParsePKCS8 parses a PKCS#8 DER encoded, unencrypted private key.
+// It returns a PrivKeyEd25519 or a PrivKeySecp256k1.
+func ParsePKCS8(der []byte) (PrivKey, error) {
+	var key pkcs8
+	if err := unmarshalDER(der, &key); err != nil {
+		return nil, err
+	}
+	if key.Version != 0 && key.Version != 1 {
+		return nil, fmt.Errorf("Unsupported PKCS#8 version %d", key.Version)
+	}
+
+	switch {
+	case key.Algorithm.Algorithm.Equal(oidEd25519):
+		var seed []byte
+		if err := unmarshalDER(key.PrivateKey, &seed); err != nil {
+			return nil, err
+		}
+		if len(seed) != ed25519SeedSize {
+			return nil, fmt.Errorf("Invalid Ed25519 private key size %d", len(seed))
+		}
+		return privKeyEd25519FromSeed(seed), nil
+	case key.Algorithm.Algorithm.Equal(oidECPublicKey):
+		if err := checkSecp256k1Algorithm(key.Algorithm); err != nil {
+			return nil, err
+		}
+		return ParseSEC1(key.PrivateKey)
+	default:
+		return nil, fmt.Errorf("Unsupported private key algorithm %v", key.Algorithm.Algorithm)
+	}
+}
+
+func privKeyEd25519FromSeed(seed []byte) PrivKeyEd25519 {
+	privKeyBytes := new([64]byte)
+	copy(privKeyBytes[:ed25519SeedSize], seed)
+	ed25519.MakePublicKey(privKeyBytes)
+	return PrivKeyEd25519(*privKeyBytes)
+}
+
+//-------------------------------------
+// SEC1
+
+// MarshalSEC1 returns the SEC1 DER encoding of priv, also known as
+// the "EC PRIVATE KEY" format of openssl.
+func MarshalSEC1(priv PrivKeySecp256k1) ([]byte, error) {
+	return marshalSEC1(priv, true)
+}
+
+func marshalSEC1(priv PrivKeySecp256k1, withCurve bool) ([]byte, error) {
+	_, pub__ := secp256k1.PrivKeyFromBytes(secp256k1.S256(), priv[:])
+	pubBytes := pub__.SerializeUncompressed()
+	key := ecPrivateKey{
+		Version:    ecPrivKeyVersion,
+		PrivateKey: priv[:],
+		PublicKey:  asn1.BitString{Bytes: pubBytes, BitLength: 8 * len(pubBytes)},
+	}
+	if withCurve {
+		key.NamedCurveOID = oidCurveSecp256k1
+	}
+	return asn1.Marshal(key)
+}
+
+// ParseSEC1 parses a SEC1 DER encoded secp256k1 private key.
+func ParseSEC1(der []byte) (PrivKeySecp256k1, error) {
+	var priv PrivKeySecp256k1
+	var key ecPrivateKey
+	if err := unmarshalDER(der, &key); err != nil {
+		return priv, err
+	}
+	if key.Version != ecPrivKeyVersion {
+		return priv, fmt.Errorf("Unsupported EC private key version %d", key.Version)
+	}
+	if len(key.NamedCurveOID) > 0 && !key.NamedCurveOID.Equal(oidCurveSecp256k1) {
+		return priv, fmt.Errorf("Unsupported EC curve %v", key.NamedCurveOID)
+	}
+	if len(key.PrivateKey) > len(priv) {
+		return priv, errors.New("Invalid secp256k1 private key size")
+	}
+	d := new(big.Int).SetBytes(key.PrivateKey)
+	if d.Sign() == 0 || d.Cmp(secp256k1.S256().N) >= 0 {
+		return priv, errors.New("Invalid secp256k1 private key")
+	}
+	copy(priv[:], intToBytes32(d))
+
+	// If the public key is included, it must match.
+	if len(key.PublicKey.Bytes) > 0 {
+		pub, err := parseSecp256k1Point(key.PublicKey.Bytes)
+		if err != nil {
+			return priv, err
+		}
+		if !priv.PubKey().Equals(pub) {
+			return priv, errors.New("EC public key does not match the private key")
+		}
+	}
+	return priv, nil
+}
+
+//-------------------------------------
+// PKIX
+
+// MarshalPKIXPubKey returns the DER encoded SubjectPublicKeyInfo of pub.
+// secp256k1 keys are written uncompressed, as openssl does.
+func MarshalPKIXPubKey(pub PubKey) ([]byte, error) {
+	var spki pkixPublicKey
+	switch pub := pub.(type) {
+	case PubKeyEd25519:
+		spki.Algorithm = pkixAlgorithm{Algorithm: oidEd25519}
+		spki.PublicKey = asn1.BitString{Bytes: pub[:], BitLength: 8 * len(pub)}
+	case PubKeySecp256k1:
+		alg, err := secp256k1Algorithm()
+		if err != nil {
+			return nil, err
+		}
+		pub__, err := secp256k1.ParsePubKey(pub[:], secp256k1.S256())
+		if err != nil {
+			return nil, err
+		}
+		pubBytes := pub__.SerializeUncompressed()
+		spki.Algorithm = alg
+		spki.PublicKey = asn1.BitString{Bytes: pubBytes, BitLength: 8 * len(pubBytes)}
+	default:
+		return nil, fmt.Errorf("PKIX is not supported for %T", pub)
+	}
+	return asn1.Marshal(spki)
+}
+
+// ParsePKIXPubKey parses a DER encoded SubjectPublicKeyInfo.
+// It returns a PubKeyEd25519 or a PubKeySecp256k1.
+func ParsePKIXPubKey(der []byte) (PubKey, error) {
+	var spki pkixPublicKey
+	if err := unmarshalDER(der, &spki); err != nil {
+		return nil, err
+	}
+	keyBytes := spki.PublicKey.RightAlign()
+
+	switch {
+	case spki.Algorithm.Algorithm.Equal(oidEd25519):
+		var pub PubKeyEd25519
+		if len(keyBytes) != len(pub) {
+			return nil, fmt.Errorf("Invalid Ed25519 public key size %d", len(keyBytes))
+		}
+		copy(pub[:], keyBytes)
+		return pub, nil
+	case spki.Algorithm.Algorithm.Equal(oidECPublicKey):
+		if err := checkSecp256k1Algorithm(spki.Algorithm); err != nil {
+			return nil, err
+		}
+		return parseSecp256k1Point(keyBytes)
+	default:
+		return nil, fmt.Errorf("Unsupported public key algorithm %v", spki.Algorithm.Algorithm)
+	}
+}
+
+// parseSecp256k1Point accepts compressed and uncompressed points.
+func parseSecp256k1Point(bz []byte) (PubKeySecp256k1, error) {
+	var pub PubKeySecp256k1
+	pub__, err := secp256k1.ParsePubKey(bz, secp256k1.S256())
+	if err != nil {
+		return pub, err
+	}
+	copy(pub[:], pub__.SerializeCompressed())
+	return pub, nil
+}
+
+// unmarshalDER is asn1.Unmarshal, but rejects trailing data.
+func unmarshalDER(der []byte, val interface{}) error {
+	rest, err := asn1.Unmarshal(der, val)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return errors.New("Trailing data after ASN.1 structure")
+	}
+	return nil
+}
+
+//-------------------------------------
+// PEM
+
+// MarshalPrivKeyPEM returns priv as a PKCS#8 "PRIVATE KEY" PEM block.
+func MarshalPrivKeyPEM(priv PrivKey) ([]byte, error) {
+	der, err := MarshalPKCS8(priv)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypePrivKey, Bytes: der}), nil
+}
+
+// ParsePrivKeyPEM parses the first private key in data, either a
+// PKCS#8 "PRIVATE KEY" or a SEC1 "EC PRIVATE KEY" block. An
+// "EC PARAMETERS" block, as written by openssl ecparam, is skipped.
+func ParsePrivKeyPEM(data []byte) (PrivKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("No private key found in PEM data")
+		}
+		if len(block.Headers) > 0 {
+			return nil, errors.New("Encrypted PEM private keys are not supported")
+		}
+		switch block.Type {
+		case pemTypePrivKey:
+			return ParsePKCS8(block.Bytes)
+		case pemTypeECPrivKey:
+			return ParseSEC1(block.Bytes)
+		case pemTypeECParams:
+			continue
+		default:
+			return nil, fmt.Errorf("Unsupported PEM block type %q", block.Type)
+		}
+	}
+}
+
+// MarshalPubKeyPEM returns pub as a PKIX "PUBLIC KEY" PEM block.
+func MarshalPubKeyPEM(pub PubKey) ([]byte, error) {
+	der, err := MarshalPKIXPubKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypePubKey, Bytes: der}), nil
+}
+
+// ParsePubKeyPEM parses a PKIX "PUBLIC KEY" PEM block.
+func ParsePubKeyPEM(data []byte) (PubKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("No public key found in PEM data")
+	}
+	if block.Type != pemTypePubKey {
+		return nil, fmt.Errorf("Unsupported PEM block type %q", block.Type)
+	}
+	return ParsePKIXPubKey(block.Bytes)
+}
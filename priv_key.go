@@ -2,11 +2,13 @@ package crypto
 
 import (
 	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"math/big"
 
 	secp256k1 "github.com/btcsuite/btcd/btcec"
 	"github.com/tendermint/ed25519"
 	"github.com/tendermint/ed25519/extra25519"
-	. "github.com/tendermint/tmlibs/common"
 )
 
 func PrivKeyFromBytes(privKeyBytes []byte) (privKey PrivKey, err error) {
@@ -18,11 +20,67 @@ func PrivKeyFromBytes(privKeyBytes []byte) (privKey PrivKey, err error) {
 
 type PrivKey interface {
 	Bytes() []byte
-	Sign(msg []byte) Signature
-	PubKey() PubKey
+	Signer
 	Equals(PrivKey) bool
 }
 
+// Signer is anything that can sign messages for a public key.
+// Implementations backed by remote services or hardware may fail,
+// so unlike most of this package, Sign reports errors instead of
+// panicking.
+type Signer interface {
+	Sign(msg []byte) (Signature, error)
+	PubKey() PubKey
+}
+
+// MustSign is a compatibility shim for callers that expect signing
+// to always succeed. It panics if the signer returns an error.
+func MustSign(signer Signer, msg []byte) Signature {
+	sig, err := signer.Sign(msg)
+	if err != nil {
+		panic(err)
+	}
+	return sig
+}
+
+// DeterministicDeriver is a private key from which child keys can be
+// derived by index. The same key and index always give the same child.
+// NOTE: Unlike BIP32, the children can't be derived from the public key.
+type DeterministicDeriver interface {
+	PrivKey
+	Derive(index int) PrivKey
+}
+
+var (
+	_ DeterministicDeriver = PrivKeyEd25519{}
+	_ DeterministicDeriver = PrivKeySecp256k1{}
+)
+
+// deriveVersion is the version of the layout hashed by deriveSeed.
+// Derived keys must never change, so a new layout needs a new version.
+const deriveVersion = 1
+
+// deriveSeed returns the Sha256 of
+//
+//	"tendermint/derive" || version || len(typ) || typ || len(parent) || parent || index
+//
+// where version and the lengths are single bytes, and index is a big
+// endian int64. Unlike an amino encoding, the layout can't change with
+// a library upgrade.
+func deriveSeed(typ string, parent []byte, index int) []byte {
+	var bz []byte
+	bz = append(bz, "tendermint/derive"...)
+	bz = append(bz, deriveVersion, byte(len(typ)))
+	bz = append(bz, typ...)
+	bz = append(bz, byte(len(parent)))
+	bz = append(bz, parent...)
+	var indexBytes [8]byte
+	binary.BigEndian.PutUint64(indexBytes[:], uint64(int64(index)))
+	bz = append(bz, indexBytes[:]...)
+	defer Zeroize(bz)
+	return Sha256(bz)
+}
+
 //-------------------------------------
 
 var _ PrivKey = PrivKeyEd25519{}
@@ -38,10 +96,10 @@ func (privKey PrivKeyEd25519) Bytes() []byte {
 	return bz
 }
 
-func (privKey PrivKeyEd25519) Sign(msg []byte) Signature {
+func (privKey PrivKeyEd25519) Sign(msg []byte) (Signature, error) {
 	privKeyBytes := [64]byte(privKey)
 	signatureBytes := ed25519.Sign(&privKeyBytes, msg)
-	return SignatureEd25519(*signatureBytes)
+	return SignatureEd25519(*signatureBytes), nil
 }
 
 func (privKey PrivKeyEd25519) PubKey() PubKey {
@@ -74,6 +132,9 @@ func (privKey PrivKeyEd25519) String() string {
 */
 
 // Deterministically generates new priv-key bytes from key.
+// The seed of the new key is Sha256 of the amino encoding of
+// {privKey, index}. It predates deriveSeed, and is kept as is so that
+// derived keys don't change.
 func (privKey PrivKeyEd25519) Generate(index int) PrivKeyEd25519 {
 	bz, err := cdc.MarshalBinaryBare(struct {
 		PrivKey [64]byte
@@ -83,9 +144,15 @@ func (privKey PrivKeyEd25519) Generate(index int) PrivKeyEd25519 {
 		panic(err)
 	}
 	newBytes := Sha256(bz)
-	var newKey [64]byte
-	copy(newKey[:], newBytes)
-	return PrivKeyEd25519(newKey)
+	newKey := new([64]byte)
+	copy(newKey[:32], newBytes)
+	ed25519.MakePublicKey(newKey)
+	return PrivKeyEd25519(*newKey)
+}
+
+// Derive implements DeterministicDeriver.
+func (privKey PrivKeyEd25519) Derive(index int) PrivKey {
+	return privKey.Generate(index)
 }
 
 func GenPrivKeyEd25519() PrivKeyEd25519 {
@@ -120,13 +187,49 @@ func (privKey PrivKeySecp256k1) Bytes() []byte {
 	return bz
 }
 
-func (privKey PrivKeySecp256k1) Sign(msg []byte) Signature {
+// Sign returns a strict DER, low-S signature of the Sha256 of msg.
+// Nonces are generated deterministically (RFC6979).
+func (privKey PrivKeySecp256k1) Sign(msg []byte) (Signature, error) {
+	return privKey.SignWithHash(msg, HashSHA256)
+}
+
+// SignWithHash is like Sign, but signs the digest of msg under h.
+// Verify with PubKeySecp256k1.VerifyBytesWithHash.
+func (privKey PrivKeySecp256k1) SignWithHash(msg []byte, h HashFunc) (Signature, error) {
+	if !h.Available() {
+		return nil, fmt.Errorf("HashFunc %d is not registered", h)
+	}
+	priv__, _ := secp256k1.PrivKeyFromBytes(secp256k1.S256(), privKey[:])
+	sig__, err := priv__.Sign(h.Sum(msg))
+	if err != nil {
+		return nil, err
+	}
+	// Serialize always encodes the low-S form.
+	return SignatureSecp256k1(sig__.Serialize()), nil
+}
+
+// SignFixed is like Sign, but returns the fixed size R || S encoding.
+func (privKey PrivKeySecp256k1) SignFixed(msg []byte) (SignatureSecp256k1Fixed, error) {
 	priv__, _ := secp256k1.PrivKeyFromBytes(secp256k1.S256(), privKey[:])
 	sig__, err := priv__.Sign(Sha256(msg))
 	if err != nil {
-		PanicSanity(err)
+		return SignatureSecp256k1Fixed{}, err
+	}
+	return newSignatureSecp256k1Fixed(sig__.R, sig__.S), nil
+}
+
+// SignRecoverable signs the Sha256 of msg like Sign, but returns a compact
+// signature from which the signer's public key can be recovered.
+// See RecoverPubKey.
+func (privKey PrivKeySecp256k1) SignRecoverable(msg []byte) (SignatureSecp256k1Recoverable, error) {
+	var sig SignatureSecp256k1Recoverable
+	priv__, _ := secp256k1.PrivKeyFromBytes(secp256k1.S256(), privKey[:])
+	sig__, err := secp256k1.SignCompact(secp256k1.S256(), priv__, Sha256(msg), true)
+	if err != nil {
+		return sig, err
 	}
-	return SignatureSecp256k1(sig__.Serialize())
+	copy(sig[:], sig__)
+	return sig, nil
 }
 
 func (privKey PrivKeySecp256k1) PubKey() PubKey {
@@ -152,18 +255,29 @@ func (privKey PrivKeySecp256k1) String() string {
 }
 */
 
-/*
 // Deterministically generates new priv-key bytes from key.
-func (key PrivKeySecp256k1) Generate(index int) PrivKeySecp256k1 {
-	newBytes := cdc.BinarySha256(struct {
-		PrivKey [64]byte
-		Index   int
-	}{key, index})
-	var newKey [64]byte
+// The new key is deriveSeed of privKey, rehashed with Sha256 until it
+// is a valid scalar in [1, N).
+func (privKey PrivKeySecp256k1) Generate(index int) PrivKeySecp256k1 {
+	newBytes := deriveSeed("secp256k1", privKey[:], index)
+	for !isValidSecp256k1Scalar(newBytes) {
+		// happens with a probability of about 2^-128
+		newBytes = Sha256(newBytes)
+	}
+	var newKey PrivKeySecp256k1
 	copy(newKey[:], newBytes)
-	return PrivKeySecp256k1(newKey)
+	return newKey
+}
+
+// Derive implements DeterministicDeriver.
+func (privKey PrivKeySecp256k1) Derive(index int) PrivKey {
+	return privKey.Generate(index)
+}
+
+func isValidSecp256k1Scalar(bz []byte) bool {
+	n := new(big.Int).SetBytes(bz)
+	return n.Sign() > 0 && n.Cmp(secp256k1.S256().N) < 0
 }
-*/
 
 func GenPrivKeySecp256k1() PrivKeySecp256k1 {
 	privKeyBytes := [32]byte{}
@@ -3,7 +3,9 @@ package crypto
 import (
 	"bytes"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"math/big"
 
 	secp256k1 "github.com/btcsuite/btcd/btcec"
 	"github.com/tendermint/ed25519"
@@ -115,10 +117,50 @@ func (pubKey PubKeySecp256k1) Bytes() []byte {
 	return bz
 }
 
+// VerifyBytes accepts SignatureSecp256k1, SignatureSecp256k1Fixed and
+// SignatureSecp256k1Recoverable signatures.
+// High-S signatures and DER signatures which aren't strictly encoded
+// are rejected, so each message has a single valid signature encoding
+// per nonce. Use SignatureSecp256k1.ToFixed to convert legacy signatures.
 func (pubKey PubKeySecp256k1) VerifyBytes(msg []byte, sig_ Signature) bool {
-	// and assert same algorithm to sign and verify
-	sig, ok := sig_.(SignatureSecp256k1)
-	if !ok {
+	return pubKey.verifyDigest(Sha256(msg), sig_)
+}
+
+// VerifyBytesWithHash is like VerifyBytes, for signatures of the
+// digest of msg under h, see PrivKeySecp256k1.SignWithHash.
+func (pubKey PubKeySecp256k1) VerifyBytesWithHash(msg []byte, sig_ Signature, h HashFunc) bool {
+	if !h.Available() {
+		return false
+	}
+	return pubKey.verifyDigest(h.Sum(msg), sig_)
+}
+
+func (pubKey PubKeySecp256k1) verifyDigest(digest []byte, sig_ Signature) bool {
+	var sig__ *secp256k1.Signature
+	switch sig := sig_.(type) {
+	case SignatureSecp256k1Recoverable:
+		// a recoverable signature is valid iff it recovers to this key
+		pub, err := recoverPubKeyFromDigest(digest, sig)
+		return err == nil && pubKey.Equals(pub)
+	case SignatureSecp256k1Fixed:
+		var ok bool
+		sig__, ok = sig.parse()
+		if !ok {
+			return false
+		}
+	case SignatureSecp256k1:
+		var err error
+		sig__, err = secp256k1.ParseDERSignature(sig[:], secp256k1.S256())
+		if err != nil {
+			return false
+		}
+		// Serialize produces strict DER with low-S, so this
+		// rejects any other encoding of the same signature.
+		if !bytes.Equal(sig__.Serialize(), sig) {
+			return false
+		}
+	default:
+		// and assert same algorithm to sign and verify
 		return false
 	}
 
@@ -126,11 +168,46 @@ func (pubKey PubKeySecp256k1) VerifyBytes(msg []byte, sig_ Signature) bool {
 	if err != nil {
 		return false
 	}
-	sig__, err := secp256k1.ParseDERSignature(sig[:], secp256k1.S256())
+	return sig__.Verify(digest, pub__)
+}
+
+// RecoverPubKey returns the public key which produced sig over msg.
+// It returns an error if sig is malformed or not low-S.
+// NOTE: Any well formed signature recovers to some public key, so the
+// result must be checked against an expected key or address,
+// e.g. with VerifyRecoverable.
+func RecoverPubKey(msg []byte, sig SignatureSecp256k1Recoverable) (PubKeySecp256k1, error) {
+	return recoverPubKeyFromDigest(Sha256(msg), sig)
+}
+
+// recoverPubKeyFromDigest accepts only the header byte of a compressed
+// key, as SignRecoverable produces, and low-S, so that each signature
+// has one encoding.
+func recoverPubKeyFromDigest(digest []byte, sig SignatureSecp256k1Recoverable) (PubKeySecp256k1, error) {
+	var pubKey PubKeySecp256k1
+	if h := sig[0]; h != secp256k1RecoverableHeader && h != secp256k1RecoverableHeader+1 {
+		return pubKey, fmt.Errorf("Invalid recovery byte %d", h)
+	}
+	if new(big.Int).SetBytes(sig[33:]).Cmp(secp256k1HalfOrder) > 0 {
+		return pubKey, errors.New("Signature is not low-S")
+	}
+	pub__, _, err := secp256k1.RecoverCompact(secp256k1.S256(), sig[:], digest)
+	if err != nil {
+		return pubKey, err
+	}
+	copy(pubKey[:], pub__.SerializeCompressed())
+	return pubKey, nil
+}
+
+// VerifyRecoverable returns true iff sig is a valid signature over msg
+// by the key with the given address.
+// This allows transactions to carry only the signature, not the pubkey.
+func VerifyRecoverable(address Address, msg []byte, sig SignatureSecp256k1Recoverable) bool {
+	pubKey, err := RecoverPubKey(msg, sig)
 	if err != nil {
 		return false
 	}
-	return sig__.Verify(Sha256(msg), pub__)
+	return bytes.Equal(pubKey.Address(), address)
 }
 
 func (pubKey PubKeySecp256k1) String() string {
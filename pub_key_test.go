@@ -42,6 +42,53 @@ func TestPubKeySecp256k1Address(t *testing.T) {
 	}
 }
 
+func TestRecoverPubKeySecp256k1(t *testing.T) {
+	for _, d := range secpDataTable {
+		privB, _ := hex.DecodeString(d.priv)
+		pubB, _ := hex.DecodeString(d.pub)
+
+		var priv PrivKeySecp256k1
+		copy(priv[:], privB)
+
+		msg := []byte("recover me")
+		sig, err := priv.SignRecoverable(msg)
+		require.Nil(t, err, "%+v", err)
+		pub, err := RecoverPubKey(msg, sig)
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, pubB, pub[:], "Expected pub keys to match")
+		assert.Equal(t, priv.PubKey().Address(), pub.Address())
+	}
+
+	// garbage recovery byte
+	var sig SignatureSecp256k1Recoverable
+	_, err := RecoverPubKey([]byte("msg"), sig)
+	assert.NotNil(t, err)
+}
+
+func TestRecoverableHeaderIsNotMalleable(t *testing.T) {
+	priv := GenPrivKeySecp256k1()
+	msg := []byte("recover me")
+	sig, err := priv.SignRecoverable(msg)
+	require.Nil(t, err, "%+v", err)
+	require.True(t, VerifyRecoverable(priv.PubKey().Address(), msg, sig))
+	require.True(t, sig[0] == 31 || sig[0] == 32, "%d", sig[0])
+
+	// the other headers with the same recovery id, uncompressed or out
+	// of range, encode the same (r, s)
+	recid := (sig[0] - 27) & 3
+	for _, h := range []byte{27 + recid, 35 + recid, 0, recid, 27, 28, 29, 30, 33, 34, 255} {
+		if h == sig[0] {
+			continue
+		}
+		flipped := sig
+		flipped[0] = h
+		assert.False(t, VerifyRecoverable(priv.PubKey().Address(), msg, flipped), "header %d", h)
+		assert.False(t, priv.PubKey().VerifyBytes(msg, flipped), "header %d", h)
+		_, err := RecoverPubKey(msg, flipped)
+		assert.NotNil(t, err, "header %d", h)
+	}
+}
+
 func TestPubKeyInvalidDataProperReturnsEmpty(t *testing.T) {
 	pk, err := PubKeyFromBytes([]byte("foo"))
 	require.NotNil(t, err, "expecting a non-nil error")
@@ -0,0 +1,186 @@
+package crypto
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	amino "github.com/tendermint/go-amino"
+)
+
+// Key algorithms are registered as KeyTypes, by this package for the
+// built-in types and by external packages in their init functions.
+// Registration panics if a name, seed byte, Go type, amino name or
+// amino prefix is already taken: a clashing amino prefix would
+// silently change the encoding, and so the address, of existing keys.
+//
+// Codecs passed to RegisterAmino get the types registered later too,
+// so a key type can be registered after this package's init.
+
+// AminoType is a concrete type, given by its zero value, and the amino
+// name it's registered under.
+type AminoType struct {
+	Name  string
+	Value interface{}
+}
+
+// KeyType describes a key algorithm.
+type KeyType struct {
+	// Name identifies the algorithm, and is its keys.CryptoAlgo.
+	Name string
+	// SeedByte identifies the algorithm in keys seed phrases. 0 if keys
+	// of this type can't be recovered from a seed phrase.
+	SeedByte byte
+
+	// PubKey must implement PubKey, PrivKey must implement PrivKey and
+	// Signatures must implement Signature. PrivKey is optional, e.g.
+	// for multisig keys. A pointer to PrivKey must implement Zeroizer,
+	// see PrivKeyFromBytesZeroizable.
+	PubKey     AminoType
+	PrivKey    AminoType
+	Signatures []AminoType
+
+	// GenPrivKey generates a random key. Optional.
+	GenPrivKey func() PrivKey
+	// GenPrivKeyFromSecret derives a key from a secret. Required with
+	// a SeedByte.
+	GenPrivKeyFromSecret func(secret []byte) PrivKey
+}
+
+var keyTypes = struct {
+	mtx      sync.Mutex
+	list     []KeyType
+	byName   map[string]KeyType
+	bySeed   map[byte]KeyType
+	amino    []AminoType
+	names    map[string]bool
+	prefixes map[amino.PrefixBytes]string
+	goTypes  map[reflect.Type]string
+	codecs   []*amino.Codec
+}{
+	byName:   map[string]KeyType{},
+	bySeed:   map[byte]KeyType{},
+	names:    map[string]bool{},
+	prefixes: map[amino.PrefixBytes]string{},
+	goTypes:  map[reflect.Type]string{},
+}
+
+var (
+	pubKeyType    = reflect.TypeOf((*PubKey)(nil)).Elem()
+	privKeyType   = reflect.TypeOf((*PrivKey)(nil)).Elem()
+	signatureType = reflect.TypeOf((*Signature)(nil)).Elem()
+)
+
+// RegisterKeyType registers a key algorithm and its types with all
+// codecs from RegisterAmino. It panics on any conflict with a
+// registered type, so it should be called from an init function.
+func RegisterKeyType(kt KeyType) {
+	keyTypes.mtx.Lock()
+	defer keyTypes.mtx.Unlock()
+
+	if kt.Name == "" {
+		panic("KeyType must have a name")
+	}
+	if _, ok := keyTypes.byName[kt.Name]; ok {
+		panic(fmt.Sprintf("KeyType %q is already registered", kt.Name))
+	}
+	if kt.SeedByte != 0 {
+		if other, ok := keyTypes.bySeed[kt.SeedByte]; ok {
+			panic(fmt.Sprintf("KeyType %q has the seed byte %X of %q", kt.Name, kt.SeedByte, other.Name))
+		}
+		if kt.GenPrivKeyFromSecret == nil {
+			panic(fmt.Sprintf("KeyType %q has a seed byte but no GenPrivKeyFromSecret", kt.Name))
+		}
+	}
+
+	// check all types before registering any
+	types := []AminoType{kt.PubKey}
+	ifaces := []reflect.Type{pubKeyType}
+	if kt.PrivKey.Value != nil {
+		types = append(types, kt.PrivKey)
+		ifaces = append(ifaces, privKeyType)
+		rt := reflect.TypeOf(kt.PrivKey.Value)
+		if _, ok := reflect.New(rt).Interface().(ZeroizablePrivKey); !ok {
+			panic(fmt.Sprintf("*%v does not implement ZeroizablePrivKey", rt))
+		}
+	}
+	for _, sig := range kt.Signatures {
+		types = append(types, sig)
+		ifaces = append(ifaces, signatureType)
+	}
+	prefixes := map[amino.PrefixBytes]bool{}
+	for i, at := range types {
+		if at.Value == nil || at.Name == "" {
+			panic(fmt.Sprintf("KeyType %q has an incomplete AminoType", kt.Name))
+		}
+		rt := reflect.TypeOf(at.Value)
+		if !rt.Implements(ifaces[i]) {
+			panic(fmt.Sprintf("%v does not implement %v", rt, ifaces[i]))
+		}
+		if other, ok := keyTypes.goTypes[rt]; ok {
+			panic(fmt.Sprintf("%v is already registered as %q", rt, other))
+		}
+		if keyTypes.names[at.Name] {
+			panic(fmt.Sprintf("Amino name %q is already registered", at.Name))
+		}
+		_, prefix := amino.NameToDisfix(at.Name)
+		if other, ok := keyTypes.prefixes[prefix]; ok || prefixes[prefix] {
+			panic(fmt.Sprintf("Amino name %q has the prefix %X of %q", at.Name, prefix, other))
+		}
+		prefixes[prefix] = true
+	}
+
+	for _, at := range types {
+		_, prefix := amino.NameToDisfix(at.Name)
+		keyTypes.names[at.Name] = true
+		keyTypes.prefixes[prefix] = at.Name
+		keyTypes.goTypes[reflect.TypeOf(at.Value)] = at.Name
+		keyTypes.amino = append(keyTypes.amino, at)
+		for _, cdc := range keyTypes.codecs {
+			cdc.RegisterConcrete(at.Value, at.Name, nil)
+		}
+	}
+	keyTypes.list = append(keyTypes.list, kt)
+	keyTypes.byName[kt.Name] = kt
+	if kt.SeedByte != 0 {
+		keyTypes.bySeed[kt.SeedByte] = kt
+	}
+}
+
+// KeyTypeByName returns the key type with the given name.
+func KeyTypeByName(name string) (KeyType, bool) {
+	keyTypes.mtx.Lock()
+	defer keyTypes.mtx.Unlock()
+	kt, ok := keyTypes.byName[name]
+	return kt, ok
+}
+
+// KeyTypeBySeedByte returns the key type with the given seed byte.
+func KeyTypeBySeedByte(b byte) (KeyType, bool) {
+	keyTypes.mtx.Lock()
+	defer keyTypes.mtx.Unlock()
+	kt, ok := keyTypes.bySeed[b]
+	return kt, ok
+}
+
+// KeyTypes returns the registered key types in registration order.
+func KeyTypes() []KeyType {
+	keyTypes.mtx.Lock()
+	defer keyTypes.mtx.Unlock()
+	return append([]KeyType{}, keyTypes.list...)
+}
+
+// RegisterAmino registers the PubKey, PrivKey and Signature interfaces
+// and the concrete types of all key types with cdc, including those
+// registered later.
+func RegisterAmino(cdc *amino.Codec) {
+	keyTypes.mtx.Lock()
+	defer keyTypes.mtx.Unlock()
+	cdc.RegisterInterface((*PubKey)(nil), nil)
+	cdc.RegisterInterface((*PrivKey)(nil), nil)
+	cdc.RegisterInterface((*Signature)(nil), nil)
+	for _, at := range keyTypes.amino {
+		cdc.RegisterConcrete(at.Value, at.Name, nil)
+	}
+	keyTypes.codecs = append(keyTypes.codecs, cdc)
+}
@@ -0,0 +1,173 @@
+package crypto
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	amino "github.com/tendermint/go-amino"
+)
+
+func TestBuiltinKeyTypes(t *testing.T) {
+	for _, name := range []string{"ed25519", "secp256k1", "schnorr", "bls12381"} {
+		kt, ok := KeyTypeByName(name)
+		require.True(t, ok, name)
+		bySeed, ok := KeyTypeBySeedByte(kt.SeedByte)
+		require.True(t, ok, name)
+		assert.Equal(t, name, bySeed.Name)
+
+		// generated keys round trip through the codec
+		privKey := kt.GenPrivKey()
+		priv2, err := PrivKeyFromBytes(privKey.Bytes())
+		require.Nil(t, err, "%+v", err)
+		assert.True(t, privKey.Equals(priv2), name)
+		assert.Equal(t, kt.GenPrivKeyFromSecret([]byte("secret")), kt.GenPrivKeyFromSecret([]byte("secret")))
+	}
+	_, ok := KeyTypeByName("multisig-threshold")
+	assert.True(t, ok)
+	_, ok = KeyTypeBySeedByte(0)
+	assert.False(t, ok)
+	_, ok = KeyTypeByName("rsa")
+	assert.False(t, ok)
+}
+
+// testPubKey and testSignature stand in for an external key type.
+type testPubKey struct{ PubKeyEd25519 }
+type testSignature struct{ SignatureEd25519 }
+
+// testPrivKey has no pointer Zeroize.
+type testPrivKey struct{ key PrivKeyEd25519 }
+
+func (k testPrivKey) Bytes() []byte                      { return k.key.Bytes() }
+func (k testPrivKey) Sign(msg []byte) (Signature, error) { return k.key.Sign(msg) }
+func (k testPrivKey) PubKey() PubKey                     { return k.key.PubKey() }
+func (k testPrivKey) Equals(other PrivKey) bool          { return k.key.Equals(other) }
+
+// snapshotKeyTypes saves the registry, and detaches the codecs from
+// RegisterAmino, which can't unregister types. The returned function
+// restores both, so that tests can register types repeatedly.
+func snapshotKeyTypes() (restore func()) {
+	keyTypes.mtx.Lock()
+	defer keyTypes.mtx.Unlock()
+	list := append([]KeyType{}, keyTypes.list...)
+	aminoTypes := append([]AminoType{}, keyTypes.amino...)
+	codecs := keyTypes.codecs
+	byName, bySeed, names, prefixes, goTypes := keyTypes.byName, keyTypes.bySeed, keyTypes.names, keyTypes.prefixes, keyTypes.goTypes
+	keyTypes.byName = map[string]KeyType{}
+	for k, v := range byName {
+		keyTypes.byName[k] = v
+	}
+	keyTypes.bySeed = map[byte]KeyType{}
+	for k, v := range bySeed {
+		keyTypes.bySeed[k] = v
+	}
+	keyTypes.names = map[string]bool{}
+	for k, v := range names {
+		keyTypes.names[k] = v
+	}
+	keyTypes.prefixes = map[amino.PrefixBytes]string{}
+	for k, v := range prefixes {
+		keyTypes.prefixes[k] = v
+	}
+	keyTypes.goTypes = map[reflect.Type]string{}
+	for k, v := range goTypes {
+		keyTypes.goTypes[k] = v
+	}
+	keyTypes.codecs = nil
+	return func() {
+		keyTypes.mtx.Lock()
+		defer keyTypes.mtx.Unlock()
+		keyTypes.list, keyTypes.amino, keyTypes.codecs = list, aminoTypes, codecs
+		keyTypes.byName, keyTypes.bySeed, keyTypes.names, keyTypes.prefixes, keyTypes.goTypes = byName, bySeed, names, prefixes, goTypes
+	}
+}
+
+func TestRegisterKeyType(t *testing.T) {
+	defer snapshotKeyTypes()()
+
+	// codecs from before the registration get the new types too
+	cdc1, cdc2 := amino.NewCodec(), amino.NewCodec()
+	RegisterAmino(cdc1)
+	RegisterAmino(cdc2)
+
+	RegisterKeyType(KeyType{
+		Name:       "test-external",
+		PubKey:     AminoType{"test/PubKeyExternal", testPubKey{}},
+		Signatures: []AminoType{{"test/SignatureExternal", testSignature{}}},
+	})
+	kt, ok := KeyTypeByName("test-external")
+	require.True(t, ok)
+	assert.Equal(t, "test/PubKeyExternal", kt.PubKey.Name)
+
+	var pub PubKey = testPubKey{GenPrivKeyEd25519().PubKey().(PubKeyEd25519)}
+	for _, c := range []*amino.Codec{cdc1, cdc2} {
+		bz, err := c.MarshalBinaryBare(pub)
+		require.Nil(t, err, "%+v", err)
+		var pub2 PubKey
+		require.Nil(t, c.UnmarshalBinaryBare(bz, &pub2))
+		assert.Equal(t, pub, pub2)
+	}
+
+	// conflicts
+	for i, kt := range []KeyType{
+		{Name: "ed25519", PubKey: AminoType{"test/PubKeyOther", struct{ testPubKey }{}}},
+		{Name: "test-seed", SeedByte: 0x01, PubKey: AminoType{"test/PubKeyOther", struct{ testPubKey }{}},
+			GenPrivKeyFromSecret: func(secret []byte) PrivKey { return GenPrivKeyEd25519FromSecret(secret) }},
+		{Name: "test-no-gen", SeedByte: 0xfe, PubKey: AminoType{"test/PubKeyOther", struct{ testPubKey }{}}},
+		{Name: "test-name", PubKey: AminoType{"tendermint/PubKeyEd25519", struct{ testPubKey }{}}},
+		{Name: "test-go-type", PubKey: AminoType{"test/PubKeyOther", PubKeyEd25519{}}},
+		{Name: "test-iface", PubKey: AminoType{"test/PubKeyOther", testSignature{}}},
+		{Name: "test-incomplete", PubKey: AminoType{"test/PubKeyOther", nil}},
+		{Name: "test-zeroize", PubKey: AminoType{"test/PubKeyOther", struct{ testPubKey }{}},
+			PrivKey: AminoType{"test/PrivKeyOther", testPrivKey{}}},
+		{Name: "", PubKey: AminoType{"test/PubKeyOther", struct{ testPubKey }{}}},
+	} {
+		assert.Panics(t, func() { RegisterKeyType(kt) }, "%d", i)
+	}
+
+	// a failed registration leaves nothing behind
+	_, ok = KeyTypeByName("test-seed")
+	assert.False(t, ok)
+	_, ok = KeyTypeByName("test-zeroize")
+	assert.False(t, ok)
+}
+
+func TestSnapshotKeyTypes(t *testing.T) {
+	before := KeyTypes()
+	restore := snapshotKeyTypes()
+	RegisterKeyType(KeyType{Name: "test-snapshot", PubKey: AminoType{"test/PubKeySnapshot", testPubKey{}}})
+	restore()
+	assert.Equal(t, len(before), len(KeyTypes()))
+	_, ok := KeyTypeByName("test-snapshot")
+	assert.False(t, ok)
+}
+
+func TestRegisterKeyTypePrefixCollision(t *testing.T) {
+	defer snapshotKeyTypes()()
+
+	// find two names with the same 4 byte prefix
+	seen := map[amino.PrefixBytes]string{}
+	var a, b string
+	for i := 0; a == ""; i++ {
+		name := fmt.Sprintf("test/Collision%d", i)
+		_, prefix := amino.NameToDisfix(name)
+		if other, ok := seen[prefix]; ok {
+			a, b = other, name
+		}
+		seen[prefix] = name
+	}
+
+	type pub struct{ testPubKey }
+	type sig struct{ testSignature }
+	assert.Panics(t, func() {
+		RegisterKeyType(KeyType{
+			Name:       "test-collision",
+			PubKey:     AminoType{a, pub{}},
+			Signatures: []AminoType{{b, sig{}}},
+		})
+	})
+	_, ok := KeyTypeByName("test-collision")
+	assert.False(t, ok)
+}
@@ -0,0 +1,274 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+
+	secp256k1 "github.com/btcsuite/btcd/btcec"
+	. "github.com/tendermint/tmlibs/common"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// This file implements BIP-340 Schnorr signatures over secp256k1.
+// https://github.com/bitcoin/bips/blob/master/bip-0340.mediawiki
+//
+// Public keys are x-only (32 bytes), signatures are 64 bytes.
+// Like PrivKeySecp256k1, the Sha256 of the message is signed.
+
+//-------------------------------------
+
+var _ PrivKey = PrivKeySchnorr{}
+
+// Implements PrivKey
+type PrivKeySchnorr [32]byte
+
+func (privKey PrivKeySchnorr) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(privKey)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (privKey PrivKeySchnorr) Sign(msg []byte) (Signature, error) {
+	sig, err := schnorrSign(privKey[:], Sha256(msg), CRandBytes(32))
+	if err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+func (privKey PrivKeySchnorr) PubKey() PubKey {
+	var pub PubKeySchnorr
+	x, _ := secp256k1.S256().ScalarBaseMult(privKey[:])
+	copy(pub[:], intToBytes32(x))
+	return pub
+}
+
+// Equals - you probably don't need to use this.
+// Runs in constant time based on length of the keys.
+func (privKey PrivKeySchnorr) Equals(other PrivKey) bool {
+	if otherSchnorr, ok := other.(PrivKeySchnorr); ok {
+		return subtle.ConstantTimeCompare(privKey[:], otherSchnorr[:]) == 1
+	} else {
+		return false
+	}
+}
+
+func GenPrivKeySchnorr() PrivKeySchnorr {
+	return PrivKeySchnorr(GenPrivKeySecp256k1())
+}
+
+// NOTE: secret should be the output of a KDF like bcrypt,
+// if it's derived from user input.
+func GenPrivKeySchnorrFromSecret(secret []byte) PrivKeySchnorr {
+	return PrivKeySchnorr(GenPrivKeySecp256k1FromSecret(secret))
+}
+
+//-------------------------------------
+
+var _ PubKey = PubKeySchnorr{}
+
+// Implements PubKey.
+// The x-coordinate of the public key point; the y-coordinate
+// is implicitly even.
+type PubKeySchnorr [32]byte
+
+// Implements Bitcoin style addresses: RIPEMD160(SHA256(pubkey))
+func (pubKey PubKeySchnorr) Address() Address {
+	hasherSHA256 := sha256.New()
+	hasherSHA256.Write(pubKey[:]) // does not error
+	sha := hasherSHA256.Sum(nil)
+
+	hasherRIPEMD160 := ripemd160.New()
+	hasherRIPEMD160.Write(sha) // does not error
+	return Address(hasherRIPEMD160.Sum(nil))
+}
+
+func (pubKey PubKeySchnorr) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(pubKey)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (pubKey PubKeySchnorr) VerifyBytes(msg []byte, sig_ Signature) bool {
+	// make sure we use the same algorithm to sign
+	sig, ok := sig_.(SignatureSchnorr)
+	if !ok {
+		return false
+	}
+	return schnorrVerify(pubKey[:], Sha256(msg), sig[:])
+}
+
+func (pubKey PubKeySchnorr) String() string {
+	return fmt.Sprintf("PubKeySchnorr{%X}", pubKey[:])
+}
+
+func (pubKey PubKeySchnorr) Equals(other PubKey) bool {
+	if otherSchnorr, ok := other.(PubKeySchnorr); ok {
+		return bytes.Equal(pubKey[:], otherSchnorr[:])
+	} else {
+		return false
+	}
+}
+
+//-------------------------------------
+
+var _ Signature = SignatureSchnorr{}
+
+// Implements Signature.
+// R.x (32 bytes) || s (32 bytes)
+type SignatureSchnorr [64]byte
+
+func (sig SignatureSchnorr) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(sig)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (sig SignatureSchnorr) IsZero() bool { return len(sig) == 0 }
+
+func (sig SignatureSchnorr) String() string { return fmt.Sprintf("/%X.../", Fingerprint(sig[:])) }
+
+func (sig SignatureSchnorr) Equals(other Signature) bool {
+	if otherSchnorr, ok := other.(SignatureSchnorr); ok {
+		return bytes.Equal(sig[:], otherSchnorr[:])
+	} else {
+		return false
+	}
+}
+
+//-------------------------------------
+// BIP-340
+
+var (
+	schnorrTagAux       = sha256.Sum256([]byte("BIP0340/aux"))
+	schnorrTagNonce     = sha256.Sum256([]byte("BIP0340/nonce"))
+	schnorrTagChallenge = sha256.Sum256([]byte("BIP0340/challenge"))
+)
+
+// taggedHash implements hash_tag(x) = SHA256(SHA256(tag) || SHA256(tag) || x).
+func taggedHash(tag [32]byte, chunks ...[]byte) []byte {
+	hasher := sha256.New()
+	hasher.Write(tag[:])
+	hasher.Write(tag[:])
+	for _, chunk := range chunks {
+		hasher.Write(chunk)
+	}
+	return hasher.Sum(nil)
+}
+
+// schnorrSign signs the 32 byte msg with the secret key seckey,
+// using aux as auxiliary randomness.
+func schnorrSign(seckey, msg, aux []byte) (sig SignatureSchnorr, err error) {
+	curve := secp256k1.S256()
+	d := new(big.Int).SetBytes(seckey)
+	if d.Sign() == 0 || d.Cmp(curve.N) >= 0 {
+		return sig, errors.New("Invalid secret key")
+	}
+	px, py := curve.ScalarBaseMult(intToBytes32(d))
+	if py.Bit(0) == 1 {
+		d.Sub(curve.N, d)
+	}
+	pBytes := intToBytes32(px)
+
+	t := intToBytes32(d)
+	for i, b := range taggedHash(schnorrTagAux, aux) {
+		t[i] ^= b
+	}
+	k := new(big.Int).SetBytes(taggedHash(schnorrTagNonce, t, pBytes, msg))
+	k.Mod(k, curve.N)
+	if k.Sign() == 0 {
+		return sig, errors.New("Invalid nonce")
+	}
+	rx, ry := curve.ScalarBaseMult(intToBytes32(k))
+	if ry.Bit(0) == 1 {
+		k.Sub(curve.N, k)
+	}
+	rBytes := intToBytes32(rx)
+
+	e := new(big.Int).SetBytes(taggedHash(schnorrTagChallenge, rBytes, pBytes, msg))
+	e.Mod(e, curve.N)
+	s := e.Mul(e, d)
+	s.Add(s, k)
+	s.Mod(s, curve.N)
+
+	copy(sig[:32], rBytes)
+	copy(sig[32:], intToBytes32(s))
+	if !schnorrVerify(pBytes, msg, sig[:]) {
+		return sig, errors.New("Created signature does not verify")
+	}
+	return sig, nil
+}
+
+// schnorrVerify returns true iff sig is a valid signature
+// of msg by the x-only public key pubkey.
+func schnorrVerify(pubkey, msg, sig []byte) bool {
+	curve := secp256k1.S256()
+	px, py, ok := liftX(pubkey)
+	if !ok {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	if r.Cmp(curve.P) >= 0 {
+		return false
+	}
+	s := new(big.Int).SetBytes(sig[32:])
+	if s.Cmp(curve.N) >= 0 {
+		return false
+	}
+	e := new(big.Int).SetBytes(taggedHash(schnorrTagChallenge, sig[:32], pubkey, msg))
+	e.Mod(e, curve.N)
+
+	// R = s*G - e*P
+	sx, sy := curve.ScalarBaseMult(intToBytes32(s))
+	ex, ey := curve.ScalarMult(px, py, intToBytes32(e))
+	ey.Sub(curve.P, ey)
+	rx, ry := curve.Add(sx, sy, ex, ey)
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		// point at infinity
+		return false
+	}
+	return ry.Bit(0) == 0 && rx.Cmp(r) == 0
+}
+
+// liftX returns the point with the given x-coordinate and an even
+// y-coordinate, if there is one.
+func liftX(xBytes []byte) (x, y *big.Int, ok bool) {
+	curve := secp256k1.S256()
+	x = new(big.Int).SetBytes(xBytes)
+	if x.Cmp(curve.P) >= 0 {
+		return nil, nil, false
+	}
+	// y^2 = x^3 + 7
+	c := new(big.Int).Exp(x, big.NewInt(3), curve.P)
+	c.Add(c, curve.B)
+	c.Mod(c, curve.P)
+	// p = 3 mod 4, so the square root is c^((p+1)/4)
+	exp := new(big.Int).Add(curve.P, big.NewInt(1))
+	exp.Rsh(exp, 2)
+	y = new(big.Int).Exp(c, exp, curve.P)
+	if new(big.Int).Exp(y, big.NewInt(2), curve.P).Cmp(c) != 0 {
+		return nil, nil, false
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(curve.P, y)
+	}
+	return x, y, true
+}
+
+// intToBytes32 returns the 32 byte big endian encoding of i.
+func intToBytes32(i *big.Int) []byte {
+	bz := make([]byte, 32)
+	ib := i.Bytes()
+	copy(bz[32-len(ib):], ib)
+	return bz
+}
@@ -0,0 +1,124 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test vectors from
+// https://github.com/bitcoin/bips/blob/master/bip-0340/test-vectors.csv
+var bip340Vectors = []struct {
+	seckey, pubkey, aux, msg, sig string
+	valid                         bool
+}{
+	{
+		seckey: "0000000000000000000000000000000000000000000000000000000000000003",
+		pubkey: "F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+		aux:    "0000000000000000000000000000000000000000000000000000000000000000",
+		msg:    "0000000000000000000000000000000000000000000000000000000000000000",
+		sig:    "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0",
+		valid:  true,
+	},
+	{
+		seckey: "B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF",
+		pubkey: "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		aux:    "0000000000000000000000000000000000000000000000000000000000000001",
+		msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		sig:    "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+		valid:  true,
+	},
+	{
+		seckey: "C90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B14E5C9",
+		pubkey: "DD308AFEC5777E13121FA72B9CC1B7CC0139715309B086C960E18FD969774EB8",
+		aux:    "C87AA53824B4D7AE2EB035A2B5BBBCCC080E76CDC6D1692C4B0B62D798E6D906",
+		msg:    "7E2D58D8B3BCDF1ABADEC7829054F90DDA9805AAB56C77333024B9D0A508B75C",
+		sig:    "5831AAEED7B44BB74E5EAB94BA9D4294C49BCF2A60728D8B4C200F50DD313C1BAB745879A5AD954A72C45A91C3A51D3C7ADEA98D82F8481E0E1E03674A6F3FB7",
+		valid:  true,
+	},
+	{
+		seckey: "0B432B2677937381AEF05BB02A66ECD012773062CF3FA2549E44F58ED2401710",
+		pubkey: "25D1DFF95105F5253C4022F628A996AD3A0D95FBF21D468A1B33F8C160D8F517",
+		aux:    "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+		msg:    "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+		sig:    "7EB0509757E246F19449885651611CB965ECC1A187DD51B64FDA1EDC9637D5EC97582B9CB13DB3933705B32BA982AF5AF25FD78881EBB32771FC5922EFC66EA3",
+		valid:  true,
+	},
+	{
+		// public key not on the curve
+		pubkey: "EEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34",
+		msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		sig:    "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B",
+		valid:  false,
+	},
+	{
+		// negated message
+		pubkey: "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		sig:    "1FA62E331EDBC21C394792D2AB1100A7B432B013DF3F6FF4F99FCB33E0E1515F28890B3EDB6E7189B630448B515CE4F8622A954CFE545735AAEA5134FCCDB2BD",
+		valid:  false,
+	},
+}
+
+func TestSchnorrBIP340Vectors(t *testing.T) {
+	for i, v := range bip340Vectors {
+		pubkey, _ := hex.DecodeString(v.pubkey)
+		msg, _ := hex.DecodeString(v.msg)
+		sig, _ := hex.DecodeString(v.sig)
+
+		if v.seckey != "" {
+			seckey, _ := hex.DecodeString(v.seckey)
+			aux, _ := hex.DecodeString(v.aux)
+
+			var priv PrivKeySchnorr
+			copy(priv[:], seckey)
+			pub := priv.PubKey().(PubKeySchnorr)
+			assert.Equal(t, pubkey, pub[:], "vector %d", i)
+
+			sig2, err := schnorrSign(seckey, msg, aux)
+			require.Nil(t, err, "vector %d: %+v", i, err)
+			assert.Equal(t, sig, sig2[:], "vector %d", i)
+		}
+		assert.Equal(t, v.valid, schnorrVerify(pubkey, msg, sig), "vector %d", i)
+	}
+}
+
+func TestSignAndValidateSchnorr(t *testing.T) {
+	privKey := GenPrivKeySchnorr()
+	pubKey := privKey.PubKey()
+
+	msg := CRandBytes(128)
+	sig, err := privKey.Sign(msg)
+	require.Nil(t, err, "%+v", err)
+
+	assert.True(t, pubKey.VerifyBytes(msg, sig))
+	assert.False(t, GenPrivKeySchnorr().PubKey().VerifyBytes(msg, sig))
+	// secp256k1 keys of the same secret are distinct
+	assert.False(t, PrivKeySecp256k1(privKey).PubKey().VerifyBytes(msg, sig))
+
+	// Mutate the signature, just one bit.
+	sigSchnorr := sig.(SignatureSchnorr)
+	sigSchnorr[7] ^= byte(0x01)
+	sig = sigSchnorr
+
+	assert.False(t, pubKey.VerifyBytes(msg, sig))
+}
+
+func TestSchnorrEncodings(t *testing.T) {
+	privKey := GenPrivKeySchnorr()
+
+	var priv2 PrivKey
+	checkAminoBinary(t, privKey, &priv2, 37)
+	assert.EqualValues(t, privKey, priv2)
+
+	var pub2 PubKey
+	checkAminoBinary(t, privKey.PubKey(), &pub2, 37)
+	assert.EqualValues(t, privKey.PubKey(), pub2)
+
+	var sig2 Signature
+	sig := MustSign(privKey, []byte("something"))
+	checkAminoBinary(t, sig, &sig2, 69)
+	assert.EqualValues(t, sig, sig2)
+}
@@ -0,0 +1,155 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+
+	amino "github.com/tendermint/go-amino"
+)
+
+// Private keys are arrays and get copied freely, so wiping one copy
+// can't wipe them all. SecretBytes and Zeroize help to keep the
+// number of copies small and their lifetime short: decode secrets
+// into a single place, use them, and wipe them.
+
+// Zeroize overwrites bz with zeros.
+func Zeroize(bz []byte) {
+	for i := range bz {
+		bz[i] = 0
+	}
+	// keep the compiler from eliding the loop
+	runtime.KeepAlive(bz)
+}
+
+// SecretBytes is a fixed size buffer for secrets. Where possible
+// (Linux), it's allocated outside the Go heap, so it's never moved or
+// copied by the garbage collector, and locked in memory, so it's
+// never swapped to disk.
+//
+// Destroy wipes and frees the buffer. It is called by a finalizer as
+// a last resort, but should be called explicitly as soon as the
+// secret isn't needed anymore.
+type SecretBytes struct {
+	mtx    sync.Mutex
+	buf    []byte
+	locked bool
+}
+
+// NewSecretBytes returns a zeroed buffer of size bytes.
+func NewSecretBytes(size int) *SecretBytes {
+	if size < 0 {
+		panic("negative SecretBytes size")
+	}
+	buf, locked := allocSecret(size)
+	s := &SecretBytes{buf: buf, locked: locked}
+	runtime.SetFinalizer(s, (*SecretBytes).Destroy)
+	return s
+}
+
+// SecretBytesFrom moves bz into a new SecretBytes, zeroing bz.
+func SecretBytesFrom(bz []byte) *SecretBytes {
+	s := NewSecretBytes(len(bz))
+	copy(s.buf, bz)
+	Zeroize(bz)
+	return s
+}
+
+// Bytes returns the buffer itself, not a copy. It must not be used
+// after Destroy. It returns nil once destroyed.
+func (s *SecretBytes) Bytes() []byte {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.buf
+}
+
+// Len returns the size of the buffer, or 0 once destroyed.
+func (s *SecretBytes) Len() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.buf)
+}
+
+// Locked returns true iff the buffer is locked in memory.
+func (s *SecretBytes) Locked() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.locked
+}
+
+// Destroy wipes and frees the buffer. It is safe to call more than
+// once.
+func (s *SecretBytes) Destroy() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.buf == nil {
+		return
+	}
+	Zeroize(s.buf)
+	freeSecret(s.buf, s.locked)
+	s.buf, s.locked = nil, false
+	runtime.SetFinalizer(s, nil)
+}
+
+//-------------------------------------
+
+// Zeroizer is a secret which can be wiped.
+type Zeroizer interface {
+	Zeroize()
+}
+
+// ZeroizablePrivKey is a pointer to a private key, which can be wiped
+// after use. See PrivKeyFromBytesZeroizable.
+type ZeroizablePrivKey interface {
+	PrivKey
+	Zeroizer
+}
+
+var (
+	_ ZeroizablePrivKey = (*PrivKeyEd25519)(nil)
+	_ ZeroizablePrivKey = (*PrivKeySecp256k1)(nil)
+	_ ZeroizablePrivKey = (*PrivKeySchnorr)(nil)
+	_ ZeroizablePrivKey = (*PrivKeyBLS12381)(nil)
+)
+
+// Zeroize overwrites the key with zeros.
+func (privKey *PrivKeyEd25519) Zeroize() { Zeroize(privKey[:]) }
+
+// Zeroize overwrites the key with zeros.
+func (privKey *PrivKeySecp256k1) Zeroize() { Zeroize(privKey[:]) }
+
+// Zeroize overwrites the key with zeros.
+func (privKey *PrivKeySchnorr) Zeroize() { Zeroize(privKey[:]) }
+
+// Zeroize overwrites the key with zeros.
+func (privKey *PrivKeyBLS12381) Zeroize() { Zeroize(privKey[:]) }
+
+// PrivKeyFromBytesZeroizable is like PrivKeyFromBytes, but decodes the
+// key directly into a pointer, so that no other copy of the key is
+// left behind once it is zeroized. The key type's pointer must
+// implement Zeroizer.
+// NOTE: Equals and type switches on key types expect values, not
+// pointers.
+func PrivKeyFromBytesZeroizable(privKeyBytes []byte) (ZeroizablePrivKey, error) {
+	for _, kt := range KeyTypes() {
+		if kt.PrivKey.Value == nil {
+			continue
+		}
+		_, prefix := amino.NameToDisfix(kt.PrivKey.Name)
+		if !bytes.HasPrefix(privKeyBytes, prefix[:]) {
+			continue
+		}
+		privKey, ok := reflect.New(reflect.TypeOf(kt.PrivKey.Value)).Interface().(ZeroizablePrivKey)
+		if !ok {
+			return nil, fmt.Errorf("%T can't be zeroized", kt.PrivKey.Value)
+		}
+		if err := cdc.UnmarshalBinaryBare(privKeyBytes, privKey); err != nil {
+			return nil, err
+		}
+		return privKey, nil
+	}
+	return nil, errors.New("Unrecognized private key encoding")
+}
@@ -0,0 +1,32 @@
+package crypto
+
+import (
+	"syscall"
+)
+
+// allocSecret maps anonymous memory for the buffer and tries to lock
+// it. Locking fails when RLIMIT_MEMLOCK is exhausted, in which case
+// the buffer is still kept out of the Go heap.
+func allocSecret(size int) (buf []byte, locked bool) {
+	if size == 0 {
+		return []byte{}, false
+	}
+	buf, err := syscall.Mmap(-1, 0, size,
+		syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		return make([]byte, size), false
+	}
+	locked = syscall.Mlock(buf) == nil
+	return buf, locked
+}
+
+func freeSecret(buf []byte, locked bool) {
+	if len(buf) == 0 {
+		return
+	}
+	if locked {
+		syscall.Munlock(buf)
+	}
+	// fails if buf came from make, after a failed mmap
+	syscall.Munmap(buf)
+}
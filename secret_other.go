@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package crypto
+
+// allocSecret allocates the buffer on the Go heap, which can't be
+// locked in memory.
+func allocSecret(size int) (buf []byte, locked bool) {
+	return make([]byte, size), false
+}
+
+func freeSecret(buf []byte, locked bool) {}
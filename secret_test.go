@@ -0,0 +1,73 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroize(t *testing.T) {
+	bz := CRandBytes(64)
+	Zeroize(bz)
+	assert.Equal(t, make([]byte, 64), bz)
+}
+
+func TestSecretBytes(t *testing.T) {
+	secret := CRandBytes(32)
+	orig := append([]byte{}, secret...)
+
+	s := SecretBytesFrom(secret)
+	assert.Equal(t, make([]byte, 32), secret, "source must be wiped")
+	assert.Equal(t, orig, s.Bytes())
+	assert.Equal(t, 32, s.Len())
+
+	s.Destroy()
+	assert.Nil(t, s.Bytes())
+	assert.Equal(t, 0, s.Len())
+	assert.False(t, s.Locked())
+
+	// Destroy is idempotent
+	s.Destroy()
+
+	empty := NewSecretBytes(0)
+	assert.Equal(t, 0, empty.Len())
+	empty.Destroy()
+}
+
+func TestSecretBytesDestroyWipes(t *testing.T) {
+	// A buffer on the Go heap, as after a failed mmap, can still be
+	// read after Destroy.
+	buf := bytes.Repeat([]byte{0xab}, 16)
+	s := &SecretBytes{buf: buf}
+	s.Destroy()
+	assert.Equal(t, make([]byte, 16), buf)
+}
+
+func TestZeroizePrivKeys(t *testing.T) {
+	privKeys := []PrivKey{
+		GenPrivKeyEd25519(),
+		GenPrivKeySecp256k1(),
+		GenPrivKeySchnorr(),
+		GenPrivKeyBLS12381(),
+	}
+	for _, privKey := range privKeys {
+		priv, err := PrivKeyFromBytesZeroizable(privKey.Bytes())
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, privKey.Bytes(), priv.Bytes())
+		assert.True(t, privKey.PubKey().Equals(priv.PubKey()))
+
+		msg := CRandBytes(32)
+		assert.True(t, privKey.PubKey().VerifyBytes(msg, MustSign(priv, msg)))
+
+		priv.Zeroize()
+		zero, err := PrivKeyFromBytesZeroizable(priv.Bytes())
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, priv, zero, "%T must be all zeros", priv)
+		assert.NotEqual(t, privKey.Bytes(), priv.Bytes())
+	}
+
+	_, err := PrivKeyFromBytesZeroizable(GenPrivKeyEd25519().PubKey().Bytes())
+	assert.NotNil(t, err)
+}
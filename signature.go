@@ -3,6 +3,9 @@ package crypto
 import (
 	"bytes"
 	"fmt"
+	"math/big"
+
+	secp256k1 "github.com/btcsuite/btcd/btcec"
 
 	. "github.com/tendermint/tmlibs/common"
 )
@@ -57,7 +60,9 @@ func SignatureEd25519FromBytes(data []byte) Signature {
 
 var _ Signature = SignatureSecp256k1{}
 
-// Implements Signature
+// Implements Signature.
+// Strict DER encoding of (r, s). Only low-S signatures are valid,
+// see PubKeySecp256k1.VerifyBytes.
 type SignatureSecp256k1 []byte
 
 func (sig SignatureSecp256k1) Bytes() []byte {
@@ -79,3 +84,122 @@ func (sig SignatureSecp256k1) Equals(other Signature) bool {
 		return false
 	}
 }
+
+// ToFixed converts a DER signature to the fixed size encoding.
+// It accepts signatures that are not strict DER or not low-S, such as
+// those created by older releases, and normalizes S to the lower half
+// of the curve order, so it can be used to migrate existing signatures.
+func (sig SignatureSecp256k1) ToFixed() (SignatureSecp256k1Fixed, error) {
+	var fixed SignatureSecp256k1Fixed
+	sig__, err := secp256k1.ParseSignature(sig, secp256k1.S256())
+	if err != nil {
+		return fixed, err
+	}
+	return newSignatureSecp256k1Fixed(sig__.R, sig__.S), nil
+}
+
+//-------------------------------------
+
+var _ Signature = SignatureSecp256k1Fixed{}
+
+// Implements Signature.
+// Fixed size encoding of a secp256k1 signature: R || S, each
+// 32 bytes big endian. Unlike DER, every (r, s) pair has exactly one
+// encoding, and only low-S signatures are valid, so the signature
+// bytes of a message can't be altered without invalidating them.
+type SignatureSecp256k1Fixed [64]byte
+
+// newSignatureSecp256k1Fixed encodes (r, s), using the low-S form.
+func newSignatureSecp256k1Fixed(r, s *big.Int) SignatureSecp256k1Fixed {
+	var sig SignatureSecp256k1Fixed
+	if s.Cmp(secp256k1HalfOrder) > 0 {
+		s = new(big.Int).Sub(secp256k1.S256().N, s)
+	}
+	rBytes, sBytes := r.Bytes(), s.Bytes()
+	copy(sig[32-len(rBytes):32], rBytes)
+	copy(sig[64-len(sBytes):], sBytes)
+	return sig
+}
+
+func (sig SignatureSecp256k1Fixed) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(sig)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (sig SignatureSecp256k1Fixed) IsZero() bool { return len(sig) == 0 }
+
+func (sig SignatureSecp256k1Fixed) String() string {
+	return fmt.Sprintf("/%X.../", Fingerprint(sig[:]))
+}
+
+func (sig SignatureSecp256k1Fixed) Equals(other Signature) bool {
+	if otherSecp, ok := other.(SignatureSecp256k1Fixed); ok {
+		return bytes.Equal(sig[:], otherSecp[:])
+	} else {
+		return false
+	}
+}
+
+// ToDER converts the signature to the strict DER encoding.
+func (sig SignatureSecp256k1Fixed) ToDER() SignatureSecp256k1 {
+	sig__ := secp256k1.Signature{
+		R: new(big.Int).SetBytes(sig[:32]),
+		S: new(big.Int).SetBytes(sig[32:]),
+	}
+	return SignatureSecp256k1(sig__.Serialize())
+}
+
+// parse returns the signature's (r, s).
+// It returns false unless 0 < r < N and 0 < s <= N/2.
+func (sig SignatureSecp256k1Fixed) parse() (*secp256k1.Signature, bool) {
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Sign() == 0 || r.Cmp(secp256k1.S256().N) >= 0 {
+		return nil, false
+	}
+	if s.Sign() == 0 || s.Cmp(secp256k1HalfOrder) > 0 {
+		return nil, false
+	}
+	return &secp256k1.Signature{R: r, S: s}, true
+}
+
+// secp256k1HalfOrder is used to tell low-S from high-S signatures.
+var secp256k1HalfOrder = new(big.Int).Rsh(secp256k1.S256().N, 1)
+
+//-------------------------------------
+
+var _ Signature = SignatureSecp256k1Recoverable{}
+
+// Implements Signature.
+// Compact recoverable signature: [recovery byte] + R (32 bytes) + S (32 bytes),
+// as produced by btcec.SignCompact. The public key that created it
+// can be recovered with RecoverPubKey.
+// The recovery byte is 27 + 4 (compressed key) + the recovery id.
+type SignatureSecp256k1Recoverable [65]byte
+
+const secp256k1RecoverableHeader = 27 + 4
+
+func (sig SignatureSecp256k1Recoverable) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(sig)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (sig SignatureSecp256k1Recoverable) IsZero() bool { return len(sig) == 0 }
+
+func (sig SignatureSecp256k1Recoverable) String() string {
+	return fmt.Sprintf("/%X.../", Fingerprint(sig[:]))
+}
+
+func (sig SignatureSecp256k1Recoverable) Equals(other Signature) bool {
+	if otherSecp, ok := other.(SignatureSecp256k1Recoverable); ok {
+		return bytes.Equal(sig[:], otherSecp[:])
+	} else {
+		return false
+	}
+}
@@ -1,8 +1,12 @@
 package crypto
 
 import (
+	"errors"
+	"math/big"
 	"testing"
 
+	secp256k1 "github.com/btcsuite/btcd/btcec"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tendermint/ed25519"
@@ -15,7 +19,8 @@ func TestSignAndValidateEd25519(t *testing.T) {
 	pubKey := privKey.PubKey()
 
 	msg := CRandBytes(128)
-	sig := privKey.Sign(msg)
+	sig, err := privKey.Sign(msg)
+	require.Nil(t, err, "%+v", err)
 
 	// Test the signature
 	assert.True(t, pubKey.VerifyBytes(msg, sig))
@@ -28,12 +33,35 @@ func TestSignAndValidateEd25519(t *testing.T) {
 	assert.False(t, pubKey.VerifyBytes(msg, sig))
 }
 
+// failingSigner is a Signer whose backend is unavailable.
+type failingSigner struct {
+	PrivKeyEd25519
+}
+
+func (failingSigner) Sign(msg []byte) (Signature, error) {
+	return nil, errors.New("signer unavailable")
+}
+
+func TestMustSign(t *testing.T) {
+	privKey := GenPrivKeyEd25519()
+	msg := CRandBytes(128)
+
+	sig := MustSign(privKey, msg)
+	assert.True(t, privKey.PubKey().VerifyBytes(msg, sig))
+
+	var signer Signer = failingSigner{privKey}
+	_, err := signer.Sign(msg)
+	assert.NotNil(t, err)
+	assert.Panics(t, func() { MustSign(signer, msg) })
+}
+
 func TestSignAndValidateSecp256k1(t *testing.T) {
 	privKey := GenPrivKeySecp256k1()
 	pubKey := privKey.PubKey()
 
 	msg := CRandBytes(128)
-	sig := privKey.Sign(msg)
+	sig, err := privKey.Sign(msg)
+	require.Nil(t, err, "%+v", err)
 
 	assert.True(t, pubKey.VerifyBytes(msg, sig))
 
@@ -45,6 +73,98 @@ func TestSignAndValidateSecp256k1(t *testing.T) {
 	assert.False(t, pubKey.VerifyBytes(msg, sig))
 }
 
+func TestSignAndValidateSecp256k1Recoverable(t *testing.T) {
+	privKey := GenPrivKeySecp256k1()
+	pubKey := privKey.PubKey()
+
+	msg := CRandBytes(128)
+	sig, err := privKey.SignRecoverable(msg)
+	require.Nil(t, err, "%+v", err)
+
+	assert.True(t, pubKey.VerifyBytes(msg, sig))
+	assert.True(t, VerifyRecoverable(pubKey.Address(), msg, sig))
+	assert.False(t, VerifyRecoverable(GenPrivKeySecp256k1().PubKey().Address(), msg, sig))
+	assert.False(t, GenPrivKeySecp256k1().PubKey().VerifyBytes(msg, sig))
+
+	// Mutate the signature, just one bit.
+	sig[7] ^= byte(0x01)
+
+	assert.False(t, pubKey.VerifyBytes(msg, sig))
+	assert.False(t, VerifyRecoverable(pubKey.Address(), msg, sig))
+}
+
+func TestSignAndValidateSecp256k1Fixed(t *testing.T) {
+	privKey := GenPrivKeySecp256k1()
+	pubKey := privKey.PubKey()
+
+	msg := CRandBytes(128)
+	sig, err := privKey.SignFixed(msg)
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, pubKey.VerifyBytes(msg, sig))
+
+	// Fixed and DER encodings are interchangeable.
+	der := MustSign(privKey, msg).(SignatureSecp256k1)
+	assert.Equal(t, der, sig.ToDER())
+	fixed, err := der.ToFixed()
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, sig, fixed)
+
+	// Mutate the signature, just one bit.
+	sig[3] ^= byte(0x01)
+	assert.False(t, pubKey.VerifyBytes(msg, sig))
+}
+
+func TestSecp256k1RejectsMalleableSignatures(t *testing.T) {
+	privKey := GenPrivKeySecp256k1()
+	pubKey := privKey.PubKey()
+	msg := CRandBytes(128)
+
+	sig, err := privKey.SignFixed(msg)
+	require.Nil(t, err, "%+v", err)
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	require.True(t, s.Cmp(secp256k1HalfOrder) <= 0, "signing must produce low-S")
+
+	// High-S is mathematically valid, but must be rejected.
+	highS := new(big.Int).Sub(secp256k1.S256().N, s)
+	var highFixed SignatureSecp256k1Fixed
+	copy(highFixed[:32], sig[:32])
+	highSBytes := highS.Bytes()
+	copy(highFixed[64-len(highSBytes):], highSBytes)
+	assert.False(t, pubKey.VerifyBytes(msg, highFixed))
+
+	// Serialize canonicalizes S, so assemble the high-S DER by hand.
+	highDER := derEncode(r.Bytes(), highS.Bytes())
+	assert.False(t, pubKey.VerifyBytes(msg, highDER))
+
+	// Legacy signatures can still be migrated.
+	migrated, err := highDER.ToFixed()
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, sig, migrated)
+	assert.True(t, pubKey.VerifyBytes(msg, migrated))
+
+	// Non-minimal (BER) encodings are rejected too.
+	ber := derEncode(append([]byte{0x00}, r.Bytes()...), s.Bytes())
+	if r.Bytes()[0] < 0x80 {
+		assert.False(t, pubKey.VerifyBytes(msg, ber))
+	}
+	assert.True(t, pubKey.VerifyBytes(msg, sig.ToDER()))
+}
+
+// derEncode assembles a DER-like signature without any canonicalization.
+func derEncode(r, s []byte) SignatureSecp256k1 {
+	if r[0]&0x80 != 0 {
+		r = append([]byte{0x00}, r...)
+	}
+	if s[0]&0x80 != 0 {
+		s = append([]byte{0x00}, s...)
+	}
+	bz := []byte{0x30, byte(4 + len(r) + len(s)), 0x02, byte(len(r))}
+	bz = append(bz, r...)
+	bz = append(bz, 0x02, byte(len(s)))
+	return append(bz, s...)
+}
+
 func TestSignatureEncodings(t *testing.T) {
 	cases := []struct {
 		privKey   PrivKey
@@ -68,7 +188,8 @@ func TestSignatureEncodings(t *testing.T) {
 		pubKey := tc.privKey.PubKey()
 
 		msg := CRandBytes(128)
-		sig := tc.privKey.Sign(msg)
+		sig, err := tc.privKey.Sign(msg)
+		require.Nil(t, err, "%+v", err)
 
 		// store as amino
 		bin, err := cdc.MarshalBinaryBare(sig)
@@ -0,0 +1,132 @@
+package crypto
+
+import (
+	stdcrypto "crypto"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+
+	secp256k1 "github.com/btcsuite/btcd/btcec"
+	xed25519 "golang.org/x/crypto/ed25519"
+)
+
+// This file converts keys to and from the types used by the Go standard
+// library (and golang.org/x/crypto), so they can be handed to packages
+// that expect a crypto.Signer or crypto.PublicKey, such as crypto/tls,
+// crypto/x509 or golang.org/x/crypto/ssh.
+//
+// Ed25519 keys convert to ed25519.PrivateKey and ed25519.PublicKey;
+// secp256k1 keys convert to *ecdsa.PrivateKey and *ecdsa.PublicKey
+// on the btcec.S256() curve.
+
+// StdPrivKey returns priv as a standard library crypto.Signer.
+func StdPrivKey(priv PrivKey) (stdcrypto.Signer, error) {
+	switch priv := priv.(type) {
+	case PrivKeyEd25519:
+		return priv.ToStd(), nil
+	case PrivKeySecp256k1:
+		return priv.ToECDSA(), nil
+	default:
+		return nil, fmt.Errorf("No standard library equivalent for %T", priv)
+	}
+}
+
+// PrivKeyFromStd converts a standard library private key, as returned
+// by StdPrivKey, back to a PrivKey.
+func PrivKeyFromStd(key stdcrypto.PrivateKey) (PrivKey, error) {
+	switch key := key.(type) {
+	case xed25519.PrivateKey:
+		if len(key) != xed25519.PrivateKeySize {
+			return nil, errors.New("Invalid Ed25519 private key size")
+		}
+		var priv PrivKeyEd25519
+		copy(priv[:], key)
+		return priv, nil
+	case *ecdsa.PrivateKey:
+		if key.Curve != secp256k1.S256() {
+			return nil, errors.New("ECDSA private key is not on the secp256k1 curve")
+		}
+		if key.D.Sign() <= 0 || key.D.Cmp(secp256k1.S256().N) >= 0 {
+			return nil, errors.New("Invalid secp256k1 private key")
+		}
+		var priv PrivKeySecp256k1
+		copy(priv[:], intToBytes32(key.D))
+		return priv, nil
+	default:
+		return nil, fmt.Errorf("Unsupported private key type %T", key)
+	}
+}
+
+// StdPubKey returns pub as a standard library public key.
+func StdPubKey(pub PubKey) (stdcrypto.PublicKey, error) {
+	switch pub := pub.(type) {
+	case PubKeyEd25519:
+		return pub.ToStd(), nil
+	case PubKeySecp256k1:
+		return pub.ToECDSA()
+	default:
+		return nil, fmt.Errorf("No standard library equivalent for %T", pub)
+	}
+}
+
+// PubKeyFromStd converts a standard library public key, as returned
+// by StdPubKey, back to a PubKey.
+func PubKeyFromStd(key stdcrypto.PublicKey) (PubKey, error) {
+	switch key := key.(type) {
+	case xed25519.PublicKey:
+		if len(key) != xed25519.PublicKeySize {
+			return nil, errors.New("Invalid Ed25519 public key size")
+		}
+		var pub PubKeyEd25519
+		copy(pub[:], key)
+		return pub, nil
+	case *ecdsa.PublicKey:
+		if key.Curve != secp256k1.S256() {
+			return nil, errors.New("ECDSA public key is not on the secp256k1 curve")
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("Invalid secp256k1 public key")
+		}
+		var pub PubKeySecp256k1
+		copy(pub[:], (*secp256k1.PublicKey)(key).SerializeCompressed())
+		return pub, nil
+	default:
+		return nil, fmt.Errorf("Unsupported public key type %T", key)
+	}
+}
+
+//-------------------------------------
+
+// ToStd returns the key as an ed25519.PrivateKey, which implements
+// crypto.Signer. Both use the same seed || public key layout.
+func (privKey PrivKeyEd25519) ToStd() xed25519.PrivateKey {
+	key := make(xed25519.PrivateKey, xed25519.PrivateKeySize)
+	copy(key, privKey[:])
+	return key
+}
+
+// ToStd returns the key as an ed25519.PublicKey.
+func (pubKey PubKeyEd25519) ToStd() xed25519.PublicKey {
+	key := make(xed25519.PublicKey, xed25519.PublicKeySize)
+	copy(key, pubKey[:])
+	return key
+}
+
+// ToECDSA returns the key as an *ecdsa.PrivateKey on the secp256k1
+// curve, which implements crypto.Signer.
+// NOTE: ecdsa signs the digest it is given, while Sign hashes msg with
+// Sha256 first, and may return high-S signatures, see
+// SignatureSecp256k1.ToFixed.
+func (privKey PrivKeySecp256k1) ToECDSA() *ecdsa.PrivateKey {
+	priv__, _ := secp256k1.PrivKeyFromBytes(secp256k1.S256(), privKey[:])
+	return priv__.ToECDSA()
+}
+
+// ToECDSA returns the key as an *ecdsa.PublicKey on the secp256k1 curve.
+func (pubKey PubKeySecp256k1) ToECDSA() (*ecdsa.PublicKey, error) {
+	pub__, err := secp256k1.ParsePubKey(pubKey[:], secp256k1.S256())
+	if err != nil {
+		return nil, err
+	}
+	return pub__.ToECDSA(), nil
+}
@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	stdcrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	xed25519 "golang.org/x/crypto/ed25519"
+)
+
+func TestStdKeysRoundTrip(t *testing.T) {
+	privKeys := []PrivKey{GenPrivKeyEd25519(), GenPrivKeySecp256k1()}
+
+	for _, priv := range privKeys {
+		signer, err := StdPrivKey(priv)
+		require.Nil(t, err, "%+v", err)
+		priv2, err := PrivKeyFromStd(signer)
+		require.Nil(t, err, "%+v", err)
+		assert.True(t, priv.Equals(priv2))
+
+		// the signer's public key matches our own conversion
+		pub, err := PubKeyFromStd(signer.Public())
+		require.Nil(t, err, "%+v", err)
+		assert.True(t, priv.PubKey().Equals(pub))
+
+		stdPub, err := StdPubKey(priv.PubKey())
+		require.Nil(t, err, "%+v", err)
+		pub2, err := PubKeyFromStd(stdPub)
+		require.Nil(t, err, "%+v", err)
+		assert.True(t, priv.PubKey().Equals(pub2))
+	}
+}
+
+func TestStdSignerEd25519(t *testing.T) {
+	priv := GenPrivKeyEd25519()
+	signer, err := StdPrivKey(priv)
+	require.Nil(t, err, "%+v", err)
+
+	msg := CRandBytes(128)
+	sigBytes, err := signer.Sign(rand.Reader, msg, stdcrypto.Hash(0))
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, priv.PubKey().VerifyBytes(msg, SignatureEd25519FromBytes(sigBytes)))
+
+	sig, err := priv.Sign(msg)
+	require.Nil(t, err, "%+v", err)
+	sigEd := sig.(SignatureEd25519)
+	assert.True(t, xed25519.Verify(priv.PubKey().(PubKeyEd25519).ToStd(), msg, sigEd[:]))
+}
+
+func TestStdSignerSecp256k1(t *testing.T) {
+	priv := GenPrivKeySecp256k1()
+	signer, err := StdPrivKey(priv)
+	require.Nil(t, err, "%+v", err)
+
+	msg := CRandBytes(128)
+	der, err := signer.Sign(rand.Reader, Sha256(msg), stdcrypto.SHA256)
+	require.Nil(t, err, "%+v", err)
+	sig, err := SignatureSecp256k1(der).ToFixed()
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, priv.PubKey().VerifyBytes(msg, sig))
+
+	fixed, err := priv.SignFixed(msg)
+	require.Nil(t, err, "%+v", err)
+	stdPub, err := priv.PubKey().(PubKeySecp256k1).ToECDSA()
+	require.Nil(t, err, "%+v", err)
+	rs, ok := fixed.parse()
+	require.True(t, ok)
+	assert.True(t, ecdsa.Verify(stdPub, Sha256(msg), rs.R, rs.S))
+}
+
+func TestStdKeysUnsupported(t *testing.T) {
+	_, err := StdPrivKey(GenPrivKeySchnorr())
+	assert.NotNil(t, err)
+	_, err = StdPubKey(GenPrivKeySchnorr().PubKey())
+	assert.NotNil(t, err)
+
+	// ECDSA keys on other curves are rejected
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err, "%+v", err)
+	_, err = PrivKeyFromStd(p256)
+	assert.NotNil(t, err)
+	_, err = PubKeyFromStd(p256.Public())
+	assert.NotNil(t, err)
+
+	_, err = PrivKeyFromStd(xed25519.PrivateKey(make([]byte, 10)))
+	assert.NotNil(t, err)
+	_, err = PubKeyFromStd(xed25519.PublicKey(make([]byte, 10)))
+	assert.NotNil(t, err)
+}
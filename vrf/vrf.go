@@ -0,0 +1,211 @@
+// Package vrf implements the ECVRF-EDWARDS25519-SHA512-TAI verifiable
+// random function of RFC 9381 (formerly draft-irtf-cfrg-vrf) on top of
+// the existing Ed25519 keys, so validators can prove VRF outputs with
+// their signing keys.
+//
+// Prove returns a proof pi and the VRF output beta for an input alpha.
+// Anyone with the public key can check with Verify that beta is the
+// unique output for alpha, while nobody without the private key can
+// predict it.
+package vrf
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+
+	"filippo.io/edwards25519"
+	crypto "github.com/tendermint/go-crypto"
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+const (
+	suiteString = 0x03
+
+	// ProofSize is the size of a proof: Gamma || c || s.
+	ProofSize = 32 + challengeSize + 32
+	// OutputSize is the size of the VRF output beta.
+	OutputSize = sha512.Size
+
+	challengeSize = 16
+)
+
+// ProofEd25519 is an ECVRF-EDWARDS25519-SHA512-TAI proof.
+type ProofEd25519 [ProofSize]byte
+
+func (proof ProofEd25519) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(proof)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (proof ProofEd25519) String() string {
+	return fmt.Sprintf("/%X.../", cmn.Fingerprint(proof[:]))
+}
+
+func (proof ProofEd25519) Equals(other ProofEd25519) bool {
+	return bytes.Equal(proof[:], other[:])
+}
+
+// ProofToHash returns the VRF output of proof, without verifying it.
+// Only use it on proofs that passed Verify, or that you created.
+func (proof ProofEd25519) ProofToHash() ([]byte, error) {
+	gamma, err := new(edwards25519.Point).SetBytes(proof[:32])
+	if err != nil {
+		return nil, err
+	}
+	return gammaToHash(gamma), nil
+}
+
+// Prove returns the proof and VRF output for alpha.
+func Prove(priv crypto.PrivKeyEd25519, alpha []byte) (ProofEd25519, []byte, error) {
+	var proof ProofEd25519
+	x, prefix, err := expandPrivKey(priv)
+	if err != nil {
+		return proof, nil, err
+	}
+	pubBytes := priv[32:]
+	// Don't trust the cached public key half of priv.
+	Y := new(edwards25519.Point).ScalarBaseMult(x)
+	if !bytes.Equal(Y.Bytes(), pubBytes) {
+		return proof, nil, errors.New("Ed25519 private key does not match its public key")
+	}
+
+	H, err := encodeToCurve(pubBytes, alpha)
+	if err != nil {
+		return proof, nil, err
+	}
+	hString := H.Bytes()
+	gamma := new(edwards25519.Point).ScalarMult(x, H)
+
+	// nonce generation of RFC 8032, section 5.1.6
+	h := sha512.New()
+	h.Write(prefix)
+	h.Write(hString)
+	k, err := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+	if err != nil {
+		return proof, nil, err
+	}
+
+	U := new(edwards25519.Point).ScalarBaseMult(k)
+	V := new(edwards25519.Point).ScalarMult(k, H)
+	cBytes := challenge(pubBytes, H, gamma, U, V)
+	c, err := challengeScalar(cBytes)
+	if err != nil {
+		return proof, nil, err
+	}
+	s := edwards25519.NewScalar().MultiplyAdd(c, x, k)
+
+	copy(proof[:32], gamma.Bytes())
+	copy(proof[32:32+challengeSize], cBytes)
+	copy(proof[32+challengeSize:], s.Bytes())
+	return proof, gammaToHash(gamma), nil
+}
+
+// Verify checks proof for alpha against pub, and returns the VRF output.
+func Verify(pub crypto.PubKeyEd25519, alpha []byte, proof ProofEd25519) ([]byte, bool) {
+	Y, err := new(edwards25519.Point).SetBytes(pub[:])
+	if err != nil {
+		return nil, false
+	}
+	// reject public keys of small order
+	if isSmallOrder(Y) {
+		return nil, false
+	}
+	gamma, err := new(edwards25519.Point).SetBytes(proof[:32])
+	if err != nil {
+		return nil, false
+	}
+	cBytes := proof[32 : 32+challengeSize]
+	c, err := challengeScalar(cBytes)
+	if err != nil {
+		return nil, false
+	}
+	s, err := edwards25519.NewScalar().SetCanonicalBytes(proof[32+challengeSize:])
+	if err != nil {
+		return nil, false
+	}
+
+	H, err := encodeToCurve(pub[:], alpha)
+	if err != nil {
+		return nil, false
+	}
+	negC := edwards25519.NewScalar().Negate(c)
+	// U = s*B - c*Y
+	U := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(negC, Y, s)
+	// V = s*H - c*Gamma
+	V := new(edwards25519.Point).VarTimeMultiScalarMult(
+		[]*edwards25519.Scalar{s, negC},
+		[]*edwards25519.Point{H, gamma},
+	)
+	if !bytes.Equal(challenge(pub[:], H, gamma, U, V), cBytes) {
+		return nil, false
+	}
+	return gammaToHash(gamma), true
+}
+
+//-------------------------------------
+
+// expandPrivKey returns the secret scalar and the nonce prefix of an
+// Ed25519 key, as in RFC 8032, section 5.1.5.
+func expandPrivKey(priv crypto.PrivKeyEd25519) (*edwards25519.Scalar, []byte, error) {
+	digest := sha512.Sum512(priv[:32])
+	x, err := edwards25519.NewScalar().SetBytesWithClamping(digest[:32])
+	if err != nil {
+		return nil, nil, err
+	}
+	return x, digest[32:], nil
+}
+
+// encodeToCurve implements ECVRF_encode_to_curve_try_and_increment.
+func encodeToCurve(pubBytes, alpha []byte) (*edwards25519.Point, error) {
+	for ctr := 0; ctr < 256; ctr++ {
+		h := sha512.New()
+		h.Write([]byte{suiteString, 0x01})
+		h.Write(pubBytes)
+		h.Write(alpha)
+		h.Write([]byte{byte(ctr), 0x00})
+		H, err := new(edwards25519.Point).SetBytes(h.Sum(nil)[:32])
+		if err != nil {
+			continue
+		}
+		return H.MultByCofactor(H), nil
+	}
+	// happens with probability 2^-256
+	return nil, errors.New("Failed to hash to curve")
+}
+
+// challenge implements ECVRF_challenge_generation.
+func challenge(pubBytes []byte, points ...*edwards25519.Point) []byte {
+	h := sha512.New()
+	h.Write([]byte{suiteString, 0x02})
+	h.Write(pubBytes)
+	for _, p := range points {
+		h.Write(p.Bytes())
+	}
+	h.Write([]byte{0x00})
+	return h.Sum(nil)[:challengeSize]
+}
+
+// challengeScalar interprets the little endian challenge as a scalar.
+func challengeScalar(cBytes []byte) (*edwards25519.Scalar, error) {
+	var buf [32]byte
+	copy(buf[:], cBytes)
+	return edwards25519.NewScalar().SetCanonicalBytes(buf[:])
+}
+
+// gammaToHash implements ECVRF_proof_to_hash.
+func gammaToHash(gamma *edwards25519.Point) []byte {
+	h := sha512.New()
+	h.Write([]byte{suiteString, 0x03})
+	h.Write(new(edwards25519.Point).MultByCofactor(gamma).Bytes())
+	h.Write([]byte{0x00})
+	return h.Sum(nil)
+}
+
+func isSmallOrder(p *edwards25519.Point) bool {
+	return new(edwards25519.Point).MultByCofactor(p).Equal(edwards25519.NewIdentityPoint()) == 1
+}
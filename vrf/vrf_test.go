@@ -0,0 +1,137 @@
+package vrf
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/ed25519"
+	crypto "github.com/tendermint/go-crypto"
+)
+
+// RFC 9381, appendix B.3
+var testVectors = []struct {
+	sk, pk, alpha, pi, beta string
+}{
+	{
+		sk:    "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+		pk:    "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+		alpha: "",
+		pi:    "8657106690b5526245a92b003bb079ccd1a92130477671f6fc01ad16f26f723f26f8a57ccaed74ee1b190bed1f479d9727d2d0f9b005a6e456a35d4fb0daab1268a1b0db10836d9826a528ca76567805",
+		beta:  "90cf1df3b703cce59e2a35b925d411164068269d7b2d29f3301c03dd757876ff66b71dda49d2de59d03450451af026798e8f81cd2e333de5cdf4f3e140fdd8ae",
+	},
+	{
+		sk:    "4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb",
+		pk:    "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+		alpha: "72",
+		pi:    "f3141cd382dc42909d19ec5110469e4feae18300e94f304590abdced48aed5933bf0864a62558b3ed7f2fea45c92a465301b3bbf5e3e54ddf2d935be3b67926da3ef39226bbc355bdc9850112c8f4b02",
+		beta:  "eb4440665d3891d668e7e0fcaf587f1b4bd7fbfe99d0eb2211ccec90496310eb5e33821bc613efb94db5e5b54c70a848a0bef4553a41befc57663b56373a5031",
+	},
+	{
+		sk:    "c5aa8df43f9f837bedb7442f31dcb7b166d38535076f094b85ce3a2e0b4458f7",
+		pk:    "fc51cd8e6218a1a38da47ed00230f0580816ed13ba3303ac5deb911548908025",
+		alpha: "af82",
+		pi:    "9bc0f79119cc5604bf02d23b4caede71393cedfbb191434dd016d30177ccbf8096bb474e53895c362d8628ee9f9ea3c0e52c7a5c691b6c18c9979866568add7a2d41b00b05081ed0f58ee5e31b3a970e",
+		beta:  "645427e5d00c62a23fb703732fa5d892940935942101e456ecca7bb217c61c452118fec1219202a0edcf038bb6373241578be7217ba85a2687f7a0310b2df19f",
+	},
+}
+
+func privKeyFromSeed(t *testing.T, seedHex string) crypto.PrivKeyEd25519 {
+	seed, err := hex.DecodeString(seedHex)
+	require.Nil(t, err)
+	privKeyBytes := new([64]byte)
+	copy(privKeyBytes[:32], seed)
+	ed25519.MakePublicKey(privKeyBytes)
+	return crypto.PrivKeyEd25519(*privKeyBytes)
+}
+
+func TestVectors(t *testing.T) {
+	for _, tc := range testVectors {
+		priv := privKeyFromSeed(t, tc.sk)
+		pub := priv.PubKey().(crypto.PubKeyEd25519)
+		assert.Equal(t, tc.pk, hex.EncodeToString(pub[:]))
+
+		alpha, _ := hex.DecodeString(tc.alpha)
+		proof, beta, err := Prove(priv, alpha)
+		require.Nil(t, err, "%+v", err)
+		assert.Equal(t, tc.pi, hex.EncodeToString(proof[:]))
+		assert.Equal(t, tc.beta, hex.EncodeToString(beta))
+
+		beta2, ok := Verify(pub, alpha, proof)
+		require.True(t, ok)
+		assert.Equal(t, beta, beta2)
+	}
+}
+
+func TestProveVerify(t *testing.T) {
+	priv := crypto.GenPrivKeyEd25519()
+	pub := priv.PubKey().(crypto.PubKeyEd25519)
+	alpha := []byte("height 42, round 0")
+
+	proof, beta, err := Prove(priv, alpha)
+	require.Nil(t, err, "%+v", err)
+	assert.Len(t, beta, OutputSize)
+
+	beta2, ok := Verify(pub, alpha, proof)
+	require.True(t, ok)
+	assert.Equal(t, beta, beta2)
+	beta3, err := proof.ProofToHash()
+	require.Nil(t, err, "%+v", err)
+	assert.Equal(t, beta, beta3)
+
+	// the output is unique: proving again gives the same proof
+	proof2, _, err := Prove(priv, alpha)
+	require.Nil(t, err, "%+v", err)
+	assert.True(t, proof.Equals(proof2))
+
+	// other inputs give other outputs
+	_, betaOther, err := Prove(priv, []byte("height 43, round 0"))
+	require.Nil(t, err, "%+v", err)
+	assert.NotEqual(t, beta, betaOther)
+}
+
+func TestVerifyFailures(t *testing.T) {
+	priv := crypto.GenPrivKeyEd25519()
+	pub := priv.PubKey().(crypto.PubKeyEd25519)
+	alpha := []byte("alpha")
+	proof, _, err := Prove(priv, alpha)
+	require.Nil(t, err, "%+v", err)
+
+	_, ok := Verify(pub, []byte("beta"), proof)
+	assert.False(t, ok)
+	_, ok = Verify(crypto.GenPrivKeyEd25519().PubKey().(crypto.PubKeyEd25519), alpha, proof)
+	assert.False(t, ok)
+
+	// flip a bit in each part of the proof
+	for _, i := range []int{0, 40, 60} {
+		tampered := proof
+		tampered[i] ^= 0x01
+		_, ok = Verify(pub, alpha, tampered)
+		assert.False(t, ok, "bit flipped at %d", i)
+	}
+
+	// s must be canonical
+	tampered := proof
+	for i := 48; i < ProofSize; i++ {
+		tampered[i] = 0xff
+	}
+	_, ok = Verify(pub, alpha, tampered)
+	assert.False(t, ok)
+
+	// small order public keys are rejected
+	var identity crypto.PubKeyEd25519
+	identity[0] = 0x01
+	_, ok = Verify(identity, alpha, proof)
+	assert.False(t, ok)
+}
+
+func TestProofEncoding(t *testing.T) {
+	proof, _, err := Prove(crypto.GenPrivKeyEd25519(), []byte("alpha"))
+	require.Nil(t, err, "%+v", err)
+
+	bz := proof.Bytes()
+	var proof2 ProofEd25519
+	require.Nil(t, cdc.UnmarshalBinaryBare(bz, &proof2))
+	assert.Equal(t, proof, proof2)
+}
@@ -0,0 +1,17 @@
+package vrf
+
+import (
+	amino "github.com/tendermint/go-amino"
+)
+
+var cdc = amino.NewCodec()
+
+func init() {
+	RegisterAmino(cdc)
+}
+
+// RegisterAmino registers the VRF proof types with cdc.
+func RegisterAmino(cdc *amino.Codec) {
+	cdc.RegisterConcrete(ProofEd25519{},
+		"tendermint/ProofEd25519VRF", nil)
+}